@@ -0,0 +1,363 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BabichevDima/aggregator/internal/database"
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+)
+
+// Values of the archive_mode setting
+const (
+	archiveModeOff     = ""
+	archiveModeStarred = "starred"
+	archiveModeAll     = "all"
+)
+
+// archiveBatchSize limits how many posts one archive run snapshots
+const archiveBatchSize = 50
+
+// archiveDir is the root of the content-addressed snapshot store
+func archiveDir(cfg *Config) (string, error) {
+	if cfg.ArchiveDir != "" {
+		return cfg.ArchiveDir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, "gator-archive"), nil
+}
+
+func archiveMode(cfg *Config) (string, error) {
+	switch cfg.ArchiveMode {
+	case archiveModeOff, archiveModeStarred, archiveModeAll:
+		return cfg.ArchiveMode, nil
+	}
+	return "", fmt.Errorf("invalid archive_mode '%s', use \"starred\" or \"all\"", cfg.ArchiveMode)
+}
+
+// storeObject writes data under its SHA-256, so identical files - the same
+// image used by many posts - are stored once. It returns the path relative
+// to dir.
+func storeObject(dir string, data []byte, ext string) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	rel := path.Join("objects", hash[:2], hash[2:]+ext)
+
+	dest := filepath.Join(dir, filepath.FromSlash(rel))
+	if _, err := os.Stat(dest); err == nil {
+		return rel, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", fmt.Errorf("create archive directory: %w", err)
+	}
+
+	// Write then rename, so a crash never leaves a truncated object behind
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("create object: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("write object: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("write object: %w", err)
+	}
+	return rel, nil
+}
+
+// archiveImage downloads an image into the store and returns its path relative to dir
+func archiveImage(ctx context.Context, fetcher *Fetcher, dir, imageURL string) (string, error) {
+	res, err := fetcher.Get(ctx, imageURL)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+	data, err := fetcher.ReadBody(res)
+	if err != nil {
+		return "", err
+	}
+
+	ext := path.Ext(res.Request.URL.Path)
+	if mediaType, _, err := mime.ParseMediaType(res.Header.Get("Content-Type")); err == nil {
+		if !strings.HasPrefix(mediaType, "image/") {
+			return "", fmt.Errorf("%s is not an image", mediaType)
+		}
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 && !slices.Contains(exts, strings.ToLower(ext)) {
+			ext = exts[0]
+		}
+	}
+	if len(ext) > 6 {
+		ext = ""
+	}
+	return storeObject(dir, data, strings.ToLower(ext))
+}
+
+// localizeImages stores the images of an HTML fragment in the archive and
+// points their src at the local copies. Images that can't be fetched keep
+// their remote URL.
+func localizeImages(ctx context.Context, fetcher *Fetcher, dir, fragment string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), bodyContext)
+	if err != nil {
+		return fragment
+	}
+
+	stored := make(map[string]string)
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "img" {
+			for i, attr := range node.Attr {
+				if attr.Namespace != "" || attr.Key != "src" {
+					continue
+				}
+				parsed, err := url.Parse(attr.Val)
+				if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+					continue
+				}
+
+				rel, ok := stored[attr.Val]
+				if !ok {
+					rel, err = archiveImage(ctx, fetcher, dir, attr.Val)
+					if err != nil {
+						fmt.Printf("Could not archive image %s: %v\n", attr.Val, err)
+						continue
+					}
+					stored[attr.Val] = rel
+				}
+				// The snapshot lives in objects/xx/ as well
+				node.Attr[i].Val = "../../" + rel
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+
+	var buf strings.Builder
+	for _, node := range nodes {
+		walk(node)
+		if err := html.Render(&buf, node); err != nil {
+			return fragment
+		}
+	}
+	return buf.String()
+}
+
+// archivePost saves a standalone HTML snapshot of a post, with its images,
+// and records it on the post. It returns the snapshot's full path. The feed
+// is named with userID's title for it, when there is a user.
+func archivePost(s *State, post database.Post, userID uuid.NullUUID) (string, error) {
+	ctx := context.Background()
+	dir, err := archiveDir(s.Config)
+	if err != nil {
+		return "", err
+	}
+
+	feedName, err := s.DB.GetFeedNameForUser(ctx, database.GetFeedNameForUserParams{
+		FeedID: post.FeedID,
+		UserID: userID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("database error: %w", err)
+	}
+
+	// Prefer the whole article over what the feed carried
+	content := post.ArticleContent.String
+	if content == "" && post.Url != "" {
+		if article, err := fetchArticle(ctx, s.Fetcher, post.Url); err == nil {
+			content = article
+		}
+	}
+	if content == "" {
+		content = post.Content.String
+	}
+	if content == "" {
+		content = post.Description.String
+	}
+	content = localizeImages(ctx, s.Fetcher, dir, content)
+
+	var doc strings.Builder
+	doc.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&doc, "<title>%s</title>\n</head>\n<body>\n", html.EscapeString(post.Title))
+	fmt.Fprintf(&doc, "<h1>%s</h1>\n<p>", html.EscapeString(post.Title))
+	if post.Url != "" {
+		fmt.Fprintf(&doc, "<a href=\"%s\">%s</a><br>\n", html.EscapeString(post.Url), html.EscapeString(post.Url))
+	}
+	fmt.Fprintf(&doc, "%s", html.EscapeString(feedName))
+	if post.PublishedAt.Valid {
+		fmt.Fprintf(&doc, ", %s", post.PublishedAt.Time.Format("2006-01-02 15:04"))
+	}
+	fmt.Fprintf(&doc, "</p>\n<article>\n%s\n</article>\n</body>\n</html>\n", content)
+
+	rel, err := storeObject(dir, []byte(doc.String()), ".html")
+	if err != nil {
+		return "", err
+	}
+	if err := s.DB.SetPostSnapshot(ctx, database.SetPostSnapshotParams{
+		ID:       post.ID,
+		Snapshot: nullString(rel),
+	}); err != nil {
+		return "", fmt.Errorf("save snapshot: %w", err)
+	}
+	return filepath.Join(dir, filepath.FromSlash(rel)), nil
+}
+
+// HandlerStarred lists starred posts with the browse options, also those of
+// feeds the user no longer follows
+func HandlerStarred(s *State, cmd Command, user database.User) error {
+	opts, err := parseBrowseArgs(cmd.Args, 10)
+	if err != nil {
+		return err
+	}
+	opts.Starred = true
+	return showPosts(s, user, opts)
+}
+
+func HandlerStar(s *State, cmd Command, user database.User) error {
+	if err := validateArgs(cmd.Args, 1, "star"); err != nil {
+		return err
+	}
+
+	postID, err := parsePostID(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	post, err := s.DB.GetPostByID(context.Background(), postID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("post '%s' does not exist", postID)
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	if err := s.DB.StarPost(context.Background(), database.StarPostParams{
+		UserID: user.ID,
+		PostID: postID,
+	}); err != nil {
+		return fmt.Errorf("failed to star post: %w", err)
+	}
+	fmt.Printf("Post %s starred\n", postID)
+
+	mode, err := archiveMode(s.Config)
+	if err != nil {
+		return err
+	}
+	if mode != archiveModeOff && !post.Snapshot.Valid {
+		snapshot, err := archivePost(s, post, uuid.NullUUID{UUID: user.ID, Valid: true})
+		if err != nil {
+			return fmt.Errorf("failed to archive post: %w", err)
+		}
+		fmt.Printf("Archived to %s\n", snapshot)
+	}
+	return nil
+}
+
+func HandlerUnstar(s *State, cmd Command, user database.User) error {
+	if err := validateArgs(cmd.Args, 1, "unstar"); err != nil {
+		return err
+	}
+
+	postID, err := parsePostID(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := s.DB.UnstarPost(context.Background(), database.UnstarPostParams{
+		UserID: user.ID,
+		PostID: postID,
+	}); err != nil {
+		return fmt.Errorf("failed to unstar post: %w", err)
+	}
+
+	// The snapshot stays: it may be the only copy left
+	fmt.Printf("Post %s unstarred\n", postID)
+	return nil
+}
+
+// HandlerArchive snapshots one post, or with no arguments every post the
+// archive_mode setting covers that has no snapshot yet
+func HandlerArchive(s *State, cmd Command) error {
+	if len(cmd.Args) > 1 {
+		return fmt.Errorf("usage: archive [post-id]")
+	}
+
+	if len(cmd.Args) == 1 {
+		postID, err := parsePostID(cmd.Args[0])
+		if err != nil {
+			return err
+		}
+		post, err := s.DB.GetPostByID(context.Background(), postID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("post '%s' does not exist", postID)
+			}
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		snapshot, err := archivePost(s, post, uuid.NullUUID{})
+		if err != nil {
+			return fmt.Errorf("failed to archive post: %w", err)
+		}
+		fmt.Printf("Archived to %s\n", snapshot)
+		return nil
+	}
+
+	mode, err := archiveMode(s.Config)
+	if err != nil {
+		return err
+	}
+	if mode == archiveModeOff {
+		return fmt.Errorf("archive_mode is not set in the config, use \"starred\" or \"all\"")
+	}
+
+	posts, err := s.DB.GetPostsToArchive(context.Background(), database.GetPostsToArchiveParams{
+		Limit:       archiveBatchSize,
+		StarredOnly: mode == archiveModeStarred,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get posts: %w", err)
+	}
+	if len(posts) == 0 {
+		fmt.Println("Nothing to archive")
+		return nil
+	}
+
+	for _, post := range posts {
+		snapshot, err := archivePost(s, post, uuid.NullUUID{})
+		if err != nil {
+			fmt.Printf("Error archiving '%s': %v\n", post.Title, err)
+			continue
+		}
+		fmt.Printf("Archived '%s' to %s\n", post.Title, snapshot)
+	}
+	if len(posts) == archiveBatchSize {
+		fmt.Println("More posts are waiting, run archive again")
+	}
+	return nil
+}
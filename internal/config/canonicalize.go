@@ -0,0 +1,177 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/BabichevDima/aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+const canonicalizeBatchSize = 500
+
+// canonicalizeFeed stores the normalized URL and url_key of a feed added before
+// URLs were normalized. If another feed turns out to have the same key the two
+// are merged; it returns the surviving feed and the one merged into it, if any.
+func canonicalizeFeed(ctx context.Context, s *State, feed database.Feed) (database.Feed, *database.Feed, error) {
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return feed, nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.DB.WithTx(tx)
+
+	newURL := normalizeURL(feed.Url)
+	existing, err := getFeedByURL(ctx, qtx, newURL)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return feed, nil, fmt.Errorf("database error: %w", err)
+	}
+
+	var merged *database.Feed
+	if err == nil && existing.ID != feed.ID {
+		// Feeds are canonicalized oldest first, so one that already has a key
+		// is older and keeps its URL
+		if existing.UrlKey.Valid {
+			if err := mergeFeeds(ctx, qtx, feed, existing); err != nil {
+				return feed, nil, err
+			}
+			return existing, &feed, tx.Commit()
+		}
+		if err := mergeFeeds(ctx, qtx, existing, feed); err != nil {
+			return feed, nil, err
+		}
+		merged = &existing
+	}
+
+	updated, err := qtx.UpdateFeedURL(ctx, database.UpdateFeedURLParams{
+		ID:     feed.ID,
+		Url:    newURL,
+		UrlKey: urlKeyParam(newURL),
+	})
+	if err != nil {
+		return feed, nil, fmt.Errorf("update feed url: %w", err)
+	}
+	return updated, merged, tx.Commit()
+}
+
+// canonicalizePosts fills in the url_key of posts saved before URLs were normalized
+func canonicalizePosts(ctx context.Context, s *State) (int, error) {
+	updated := 0
+	for {
+		posts, err := s.DB.GetPostsWithoutURLKey(ctx, canonicalizeBatchSize)
+		if err != nil {
+			return updated, fmt.Errorf("failed to get posts: %w", err)
+		}
+		if len(posts) == 0 {
+			return updated, nil
+		}
+
+		for _, post := range posts {
+			if err := s.DB.SetPostURL(ctx, database.SetPostURLParams{
+				ID:     post.ID,
+				Url:    normalizeURL(post.Url),
+				UrlKey: urlKeyParam(post.Url),
+			}); err != nil {
+				return updated, fmt.Errorf("update post url: %w", err)
+			}
+			updated++
+		}
+	}
+}
+
+// mergeDuplicatePosts folds posts of a feed that are the same item under
+// differently spelled links into the oldest of them, keeping everyone's read
+// state, stars and tags. Posts that only share a link stay apart.
+func mergeDuplicatePosts(ctx context.Context, s *State) (int, error) {
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.DB.WithTx(tx)
+
+	candidates, err := qtx.GetDuplicateCandidates(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get duplicate posts: %w", err)
+	}
+
+	merged := 0
+	var keepers []database.GetDuplicateCandidatesRow
+	for i, post := range candidates {
+		// Candidates come grouped by feed and url_key, oldest first
+		if i == 0 || post.FeedID != candidates[i-1].FeedID || post.UrlKey != candidates[i-1].UrlKey {
+			keepers = keepers[:0]
+		}
+
+		keeper := -1
+		for j := range keepers {
+			if samePostItem(keepers[j].Guid, post.Guid, post.UrlKey) {
+				keeper = j
+				break
+			}
+		}
+		if keeper < 0 {
+			keepers = append(keepers, post)
+			continue
+		}
+
+		if err := movePostState(ctx, qtx, keepers[keeper].ID, post.ID); err != nil {
+			return 0, err
+		}
+		if err := qtx.DeletePost(ctx, post.ID); err != nil {
+			return 0, fmt.Errorf("delete duplicate post: %w", err)
+		}
+		merged++
+	}
+	return merged, tx.Commit()
+}
+
+// HandlerCanonicalize normalizes the URLs stored before normalization was
+// introduced and merges the duplicate feeds and posts this uncovers. It is
+// safe to run more than once.
+func HandlerCanonicalize(s *State, cmd Command) error {
+	if err := validateArgs(cmd.Args, 0, "canonicalize"); err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	feeds, err := s.DB.GetAllFeeds(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get feeds: %w", err)
+	}
+
+	gone := make(map[uuid.UUID]bool)
+	feedsUpdated, feedsMerged := 0, 0
+	for _, feed := range feeds {
+		if feed.UrlKey.Valid || gone[feed.ID] {
+			continue
+		}
+		kept, merged, err := canonicalizeFeed(ctx, s, feed)
+		if err != nil {
+			return fmt.Errorf("canonicalize %s: %w", feed.Url, err)
+		}
+		if merged != nil {
+			gone[merged.ID] = true
+			feedsMerged++
+			fmt.Printf("Merged feed '%s' into '%s'\n", merged.Name, kept.Name)
+		}
+		if kept.ID == feed.ID {
+			feedsUpdated++
+		}
+	}
+
+	postsUpdated, err := canonicalizePosts(ctx, s)
+	if err != nil {
+		return err
+	}
+	postsMerged, err := mergeDuplicatePosts(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Feeds: %d normalized, %d merged\n", feedsUpdated, feedsMerged)
+	fmt.Printf("Posts: %d normalized, %d duplicates merged\n", postsUpdated, postsMerged)
+	return nil
+}
@@ -1,24 +1,30 @@
 package config
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
-	"os"
 	"io"
+	"mime"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
 	"html"
 	"strconv"
+	"unicode/utf8"
 
 	"net/http"
 
 	"github.com/BabichevDima/aggregator/internal/database"
 	"github.com/google/uuid"
+	"golang.org/x/net/html/charset"
 )
 const (
 	configFileName = ".gatorconfig.json"
@@ -28,11 +34,45 @@ const (
 type Config struct {
 	DBUrl           string `json:"db_url"`
     CurrentUserName string `json:"current_user_name"`
+
+	// HTTP client settings, all optional
+	HTTPTimeout string   `json:"http_timeout,omitempty"`  // e.g. "30s"
+	MaxBodySize int64    `json:"max_body_size,omitempty"` // in bytes
+	UserAgent   string   `json:"user_agent,omitempty"`
+	HTTPProxy   string   `json:"http_proxy,omitempty"`
+	CABundles   []string `json:"ca_bundles,omitempty"` // paths to extra PEM files
+
+	// MarkUpdatedUnread makes posts edited upstream show up as unread again
+	MarkUpdatedUnread bool `json:"mark_updated_unread,omitempty"`
+
+	// DownloadDir is where the download command saves enclosures (default ~/gator-downloads)
+	DownloadDir string `json:"download_dir,omitempty"`
+
+	// ArchiveMode snapshots posts for offline reading: "starred" or "all"
+	ArchiveMode string `json:"archive_mode,omitempty"`
+	// ArchiveDir is where snapshots and their images are stored (default ~/gator-archive)
+	ArchiveDir string `json:"archive_dir,omitempty"`
+
+	// Retention: prune posts older than RetentionDays or beyond the newest
+	// RetentionItems of each feed. 0 keeps everything; feeds can override both.
+	RetentionDays  int `json:"retention_days,omitempty"`
+	RetentionItems int `json:"retention_items,omitempty"`
+	// RetentionKeepUnread keeps posts that a follower has not read yet
+	RetentionKeepUnread bool `json:"retention_keep_unread,omitempty"`
+
+	// Feeds nobody follows are reaped after OrphanGraceDays (default 30):
+	// OrphanAction "delete" (the default) or "archive", which first writes
+	// the feed and its posts to the archive directory
+	OrphanGraceDays int    `json:"orphan_grace_days,omitempty"`
+	OrphanAction    string `json:"orphan_action,omitempty"`
 }
 
 type State struct {
 	Config *Config
 	DB  *database.Queries
+	// Conn is the underlying connection pool, used to run queries in a transaction
+	Conn *sql.DB
+	Fetcher *Fetcher
 }
 
 type Command struct {
@@ -48,19 +88,65 @@ type Commands struct {
 
 // TODO: RSS
 type RSSFeed struct {
+	Base    string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
 	Channel struct {
+		Base        string    `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
 		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
+		// Link is picked from Links, since atom:link elements share the local name
+		Link        string    `xml:"-"`
+		Links       []rssLink `xml:"link"`
 		Description string    `xml:"description"`
+		Language    string    `xml:"language"`
+		Image       struct {
+			URL string `xml:"url"`
+		} `xml:"image"`
 		Item        []RSSItem `xml:"item"`
 	} `xml:"channel"`
+
+	// MovedTo is set when the feed URL answered with a chain of permanent redirects
+	MovedTo string `xml:"-"`
+}
+
+type rssLink struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
 }
 
 type RSSItem struct {
+	Base        string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	GUID        string `xml:"guid"`
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	// RawLink is Link as the feed had it, before resolveFeedURLs made it absolute
+	RawLink string `xml:"-"`
+
+	// Content is the full article, Description is often only a teaser
+	Content    string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Creator    string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Author     string   `xml:"author"`
+	Categories []string `xml:"category"`
+	Comments   string   `xml:"comments"`
+
+	// Podcast and media attachments, see Attachments
+	Enclosures      []rssEnclosure   `xml:"enclosure"`
+	MediaContent    []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnails []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaGroups     []mediaGroup     `xml:"http://search.yahoo.com/mrss/ group"`
+	ITunesDuration  string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ITunesEpisode   string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	ITunesImage     struct {
+		Href string `xml:"href,attr"`
+	} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+}
+
+// PostAuthor prefers dc:creator, which is a name, over RSS author, which is an email
+func (item RSSItem) PostAuthor() string {
+	if creator := strings.TrimSpace(item.Creator); creator != "" {
+		return creator
+	}
+	return strings.TrimSpace(item.Author)
 }
 // TODO: RSS
 
@@ -155,7 +241,8 @@ func HandlerRegister(s *State, cmd Command) error {
 	}
 
 	username := cmd.Args[0]
-    if err := createUser(s.DB, username); err != nil {
+	user, err := createUser(s.DB, username)
+	if err != nil {
 		return fmt.Errorf("registration failed: %w", err)
 	}
 
@@ -165,6 +252,9 @@ func HandlerRegister(s *State, cmd Command) error {
 	}
 
     fmt.Printf("User '%s' created successfully\n", username)
+	if user.IsAdmin {
+		fmt.Println("As the first user, you are the admin and can edit or delete any feed")
+	}
 	return nil
 }
 
@@ -203,8 +293,8 @@ func validateArgs(args []string, expected int, cmdName string) error {
 	return nil
 }
 
-func createUser(db *database.Queries, username string) error {
-	_, err := db.CreateUser(context.Background(), database.CreateUserParams{
+func createUser(db *database.Queries, username string) (database.User, error) {
+	user, err := db.CreateUser(context.Background(), database.CreateUserParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -213,12 +303,12 @@ func createUser(db *database.Queries, username string) error {
 
 	if err != nil {
 		if strings.Contains(err.Error(), "duplicate key") {
-			return fmt.Errorf("user '%s' already exists", username)
+			return user, fmt.Errorf("user '%s' already exists", username)
 		}
-		return fmt.Errorf("database error: %w", err)
+		return user, fmt.Errorf("database error: %w", err)
 	}
 
-	return nil
+	return user, nil
 }
 
 func Read() (*Config, error) {
@@ -275,37 +365,38 @@ func write(cfg Config) error {
 	return nil
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-
-	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+func fetchFeed(ctx context.Context, fetcher *Fetcher, feedURL string) (*RSSFeed, error) {
+	res, err := fetcher.Get(ctx, feedURL)
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-
-	// set request headers
-	req.Header.Set("User-Agent", "gator")
-
-	// create a new client and make the request
-	client := &http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("making request: %w", err)
+		return nil, err
 	}
 	defer res.Body.Close()
 
     // Check status code
+	if res.StatusCode == http.StatusGone {
+		return nil, errFeedGone
+	}
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+		return nil, &retryAfterError{
+			StatusCode: res.StatusCode,
+			RetryAt:    parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+		}
+	}
     if res.StatusCode != http.StatusOK {
         return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
     }
 
-	data, err := io.ReadAll(res.Body)
+	data, err := fetcher.ReadBody(res)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, err
 	}
 
-	var rssFeed RSSFeed
-	if err := xml.Unmarshal(data, &rssFeed); err != nil {
-		return nil, fmt.Errorf("parsing XML: %w", err)
+	rssFeed, err := parseFeed(data, res.Header.Get("Content-Type"))
+	if err != nil {
+		if errors.Is(err, errNotAFeed) {
+			return nil, &htmlPageError{URL: res.Request.URL.String(), Body: data}
+		}
+		return nil, err
 	}
 
 	// Decode HTML entities in text fields
@@ -316,49 +407,232 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
         item := &rssFeed.Channel.Item[i]
         item.Title = html.UnescapeString(item.Title)
         item.Description = html.UnescapeString(item.Description)
+        item.Content = html.UnescapeString(item.Content)
+        item.Creator = html.UnescapeString(item.Creator)
+        item.Author = html.UnescapeString(item.Author)
+        for j := range item.Categories {
+            item.Categories[j] = html.UnescapeString(item.Categories[j])
+        }
     }
 
-	return &rssFeed, nil
+	// Relative links are useless once stored, make them absolute
+	resolveFeedURLs(rssFeed, res.Request.URL.String())
+
+	rssFeed.MovedTo = permanentRedirectTarget(res, feedURL)
+
+	return rssFeed, nil
+}
+
+// decodeXML unmarshals an XML document in any charset into v.
+// A non-UTF-8 charset from the Content-Type header wins over the XML
+// declaration (RFC 7303); otherwise the declared encoding is used, unless the
+// server promised UTF-8 and the bytes really are valid UTF-8.
+func decodeXML(data []byte, contentType string, v any) error {
+	var input io.Reader = bytes.NewReader(data)
+	ignoreDeclaration := false
+
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		label := params["charset"]
+		switch {
+		case !isUTF8Label(label):
+			reader, err := charset.NewReaderLabel(label, input)
+			if err != nil {
+				return fmt.Errorf("unsupported charset %q: %w", label, err)
+			}
+			input = reader
+			ignoreDeclaration = true
+		case utf8.Valid(data):
+			ignoreDeclaration = true
+		}
+	}
+
+	decoder := xml.NewDecoder(input)
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		if ignoreDeclaration {
+			return input, nil
+		}
+		return charset.NewReaderLabel(label, input)
+	}
+	return decoder.Decode(v)
+}
+
+func isUTF8Label(label string) bool {
+	return strings.EqualFold(label, "utf-8") || strings.EqualFold(label, "utf8")
+}
+
+// errFeedGone is returned by fetchFeed when the publisher answered 410 Gone
+var errFeedGone = errors.New("feed is gone (410)")
+
+// permanentRedirectTarget returns the final URL of the response when every hop
+// that led to it was a permanent redirect (301 or 308), and "" otherwise.
+func permanentRedirectTarget(res *http.Response, feedURL string) string {
+	final := res.Request
+	if final == nil || final.Response == nil {
+		return ""
+	}
+
+	// Each redirected request keeps the response that caused it, which in turn
+	// points at the previous request - walk the chain back to the original URL.
+	for req := final; req.Response != nil; req = req.Response.Request {
+		code := req.Response.StatusCode
+		if code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+			return ""
+		}
+	}
+
+	if final.URL.String() == feedURL {
+		return ""
+	}
+	return final.URL.String()
+}
+
+// retryAfterError is returned by fetchFeed when the publisher asks us to back off
+// (429 Too Many Requests or 503 Service Unavailable).
+type retryAfterError struct {
+	StatusCode int
+	RetryAt    time.Time
+}
+
+func (e *retryAfterError) Error() string {
+	return fmt.Sprintf("status code %d, retry after %s", e.StatusCode, e.RetryAt.Format(time.RFC1123))
+}
+
+const (
+	// defaultRetryAfter is used when a 429/503 response has no usable Retry-After header
+	defaultRetryAfter = 30 * time.Minute
+	// maxRetryAfter caps absurd Retry-After values so a feed is never parked forever
+	maxRetryAfter = 7 * 24 * time.Hour
+)
+
+// parseRetryAfter understands both forms of the Retry-After header:
+// delay in seconds ("120") and an HTTP date ("Wed, 21 Oct 2015 07:28:00 GMT").
+func parseRetryAfter(value string, now time.Time) time.Time {
+	value = strings.TrimSpace(value)
+
+	delay := defaultRetryAfter
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds >= 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = date.Sub(now)
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return now.Add(delay)
 }
 
 func HandlerAddFeed(s *State, cmd Command, user database.User) error {
-	if err := validateArgs(cmd.Args, 2, "addfeed"); err != nil {
-		return err
+	if len(cmd.Args) < 1 || len(cmd.Args) > 2 {
+		return errors.New("usage: addfeed [name] <url>")
+	}
+
+	name, rawURL := "", cmd.Args[0]
+	if len(cmd.Args) == 2 {
+		name, rawURL = cmd.Args[0], cmd.Args[1]
+	}
+
+	ctx := context.Background()
+
+	if existing, err := getFeedByURL(ctx, s.DB, rawURL); err == nil {
+		return fmt.Errorf("feed '%s' already exists as %s, use follow", existing.Name, existing.Url)
+	}
+
+	// Fetch the feed first so broken URLs never get stored.
+	// The URL may be a homepage - find the feed it advertises
+	feedURL, rssFeed, err := resolveFeedURL(ctx, s, rawURL)
+	if err != nil {
+		return fmt.Errorf("addfeed failed: %w", err)
+	}
+	feedURL = normalizeURL(feedURL)
+
+	if name == "" {
+		name = strings.TrimSpace(rssFeed.Channel.Title)
+	}
+	if name == "" {
+		name = feedURL
 	}
 
-	feed, err := s.DB.CreateFeed(context.Background(), database.CreateFeedParams{
+	// Feed and follow are created together or not at all
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.DB.WithTx(tx)
+
+	metadata := feedMetadata(rssFeed)
+	feed, err := qtx.CreateFeed(ctx, database.CreateFeedParams{
 		ID:			uuid.New(),
 		CreatedAt:	time.Now(),
 		UpdatedAt:	time.Now(),
-		Name:		cmd.Args[0],
-		Url:		cmd.Args[1],
+		Name:		name,
+		Url:		feedURL,
 		UserID:		user.ID,
+		Title:		metadata.Title,
+		Description:	metadata.Description,
+		SiteUrl:	metadata.SiteUrl,
+		Language:	metadata.Language,
+		ImageUrl:	metadata.ImageUrl,
+		UrlKey:		urlKeyParam(feedURL),
 	})
-
-	fmt.Printf("Created feed: %+v\n", feed)
-
 	if err != nil {
 		if strings.Contains(err.Error(), "duplicate key") {
-			return fmt.Errorf("url '%s' already exists", cmd.Args[1])
+			return fmt.Errorf("url '%s' already exists", feedURL)
 		}
 		return fmt.Errorf("database error: %w", err)
 	}
 
-	feedFollow, err := s.DB.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+	feedFollow, err := qtx.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
 		ID:			uuid.New(),
 		CreatedAt:	time.Now(),
 		UpdatedAt:	time.Now(),
 		UserID:		user.ID,
 		FeedID:		feed.ID,
 	})
+	if err != nil {
+		return fmt.Errorf("failed to follow feed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
 
-	fmt.Println("NEW feedFollow:", feedFollow)
+	fmt.Printf("Created feed: %s (%s)\n", feed.Name, feed.Url)
+	if feed.Title.Valid {
+		fmt.Printf("Title: %s\n", feed.Title.String)
+	}
+	fmt.Printf("User '%s' now follows '%s'\n", feedFollow.UserName, feedFollow.FeedName)
 
 	return nil
 }
 
+// feedMetadata extracts the channel details we keep on the feed row
+func feedMetadata(rssFeed *RSSFeed) database.UpdateFeedMetadataParams {
+	return database.UpdateFeedMetadataParams{
+		Title:       nullString(rssFeed.Channel.Title),
+		Description: nullString(rssFeed.Channel.Description),
+		SiteUrl:     nullString(rssFeed.Channel.Link),
+		Language:    nullString(rssFeed.Channel.Language),
+		ImageUrl:    nullString(rssFeed.Channel.Image.URL),
+	}
+}
+
+func nullString(value string) sql.NullString {
+	value = strings.TrimSpace(value)
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
 func MiddlewareLoggedIn(handler func(s *State, cmd Command, user database.User) error) func(*State, Command) error {
     return func(s *State, cmd Command) error {
+        if s.Config.CurrentUserName == "" {
+            return errors.New("not logged in, use login or register")
+        }
         user, err := getUser(s.DB, s.Config.CurrentUserName)
         if err != nil {
             return fmt.Errorf("failed to get user: %w", err)
@@ -378,12 +652,42 @@ func HandlerFeeds(s *State, cmd Command) error {
 		fmt.Println("Information about feed number - ", i)
 		fmt.Println("Feed's name:", feeds[i].Name)
 		fmt.Println("Feed's url:", feeds[i].Url)
+		if feeds[i].Title.Valid {
+			fmt.Println("Feed's title:", feeds[i].Title.String)
+		}
+		if feeds[i].SiteUrl.Valid {
+			fmt.Println("Feed's site:", feeds[i].SiteUrl.String)
+		}
 		fmt.Println("Feed's username:", feeds[i].Username)
+		if feeds[i].DeactivatedAt.Valid {
+			fmt.Println("Feed's status: deactivated since", feeds[i].DeactivatedAt.Time.Format("2006-01-02 15:04"))
+		}
+		if feeds[i].OrphanedAt.Valid {
+			fmt.Println("Feed's status: no followers since", feeds[i].OrphanedAt.Time.Format("2006-01-02 15:04"))
+		}
+		if feeds[i].NextFetchAt.Valid && feeds[i].NextFetchAt.Time.After(time.Now()) {
+			fmt.Println("Feed's next fetch:", feeds[i].NextFetchAt.Time.Format("2006-01-02 15:04"))
+			fmt.Println("Feed's fetch deferred because:", feeds[i].LastFetchError.String)
+		}
 		fmt.Println()
 	}
 	return nil
 }
 
+// urlKeyParam is the url_key argument of queries that look feeds up by URL
+func urlKeyParam(rawURL string) sql.NullString {
+	return sql.NullString{String: urlKey(rawURL), Valid: true}
+}
+
+// getFeedByURL finds a feed however its URL is spelled: http or https,
+// with or without www., a trailing slash or tracking parameters
+func getFeedByURL(ctx context.Context, db *database.Queries, rawURL string) (database.Feed, error) {
+	return db.GetFeedByURL(ctx, database.GetFeedByURLParams{
+		UrlKey: urlKeyParam(rawURL),
+		Url:    strings.TrimSpace(rawURL),
+	})
+}
+
 func HandlerFollow(s *State, cmd Command, user database.User) error {
 	if err := validateArgs(cmd.Args, 1, "follow"); err != nil {
 		return err
@@ -391,7 +695,7 @@ func HandlerFollow(s *State, cmd Command, user database.User) error {
 
 	url := cmd.Args[0]
 
-	currentFeed, err := s.DB.GetFeedByURL(context.Background(), url)
+	currentFeed, err := getFeedByURL(context.Background(), s.DB, url)
 	if err != nil {
 		return fmt.Errorf("failed wth next reason: %w", err)
 	}
@@ -404,6 +708,13 @@ func HandlerFollow(s *State, cmd Command, user database.User) error {
 		UserID:		user.ID,
 		FeedID:		currentFeed.ID,
 	})
+	if err != nil {
+		return fmt.Errorf("failed to follow feed: %w", err)
+	}
+	// The feed has a follower again, stop its grace period
+	if err := s.DB.MarkOrphanedFeeds(context.Background()); err != nil {
+		return fmt.Errorf("failed to update orphaned feeds: %w", err)
+	}
 
 	fmt.Println("NEW feed:", feed)
 
@@ -426,8 +737,28 @@ func HandlerFollowing(s *State, cmd Command, user database.User) error {
 	} else {
 		fmt.Println("The names of the feeds the current user is following:")
 
+		// Feeds come sorted by folder, the ones without a folder first
+		folder := ""
 		for i, _ := range FeedFollowsForUser {
-			fmt.Println("*", FeedFollowsForUser[i].FeedName)
+			follow := FeedFollowsForUser[i]
+			if follow.FolderName.String != folder {
+				folder = follow.FolderName.String
+				fmt.Printf("\n%s/\n", folder)
+			}
+			if folder != "" {
+				fmt.Print("  ")
+			}
+			line := "* " + follow.FeedName
+			if follow.Priority != 0 {
+				line += fmt.Sprintf(" (priority %d)", follow.Priority)
+			}
+			if follow.Muted {
+				line += " [muted]"
+			}
+			if follow.Notify {
+				line += " [notify]"
+			}
+			fmt.Println(line)
 		}
 	}
 
@@ -440,18 +771,112 @@ func HandlerUnfollow(s *State, cmd Command, user database.User) error {
 		return err
 	}
 
-	if err := s.DB.DeleteFeedFollowByURL(context.Background(), database.DeleteFeedFollowByURLParams{
+	unfollowed, err := s.DB.DeleteFeedFollowByURL(context.Background(), database.DeleteFeedFollowByURLParams{
 		UserID:	user.ID,
-		Url:	cmd.Args[0],
-	}); err != nil {
+		UrlKey:	urlKeyParam(cmd.Args[0]),
+		Url:	strings.TrimSpace(cmd.Args[0]),
+	})
+	if err != nil {
 		return fmt.Errorf("failed to unfollow: %w", err)
 	}
+	if unfollowed == 0 {
+		return fmt.Errorf("you are not following '%s'", cmd.Args[0])
+	}
+	// The feed may have lost its last follower, start its grace period
+	if err := s.DB.MarkOrphanedFeeds(context.Background()); err != nil {
+		return fmt.Errorf("failed to update orphaned feeds: %w", err)
+	}
 
 	fmt.Printf("Unfollowed feed with URL: %s\n", cmd.Args[0])
 
 	return nil
 }
 
+const followSetUsage = "usage: followset <feed-url> [title=<name>|default] [priority=<n>] [muted=true|false] [notify=true|false]"
+
+// HandlerFollowSet shows or changes the current user's settings for a followed feed
+func HandlerFollowSet(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 1 {
+		return errors.New(followSetUsage)
+	}
+
+	feed, err := getFeedByURL(context.Background(), s.DB, cmd.Args[0])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("feed with URL '%s' does not exist", cmd.Args[0])
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	follow, err := s.DB.GetFeedFollow(context.Background(), database.GetFeedFollowParams{
+		UserID: user.ID,
+		FeedID: feed.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("you are not following '%s'", cmd.Args[0])
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	if len(cmd.Args) > 1 {
+		params := database.UpdateFollowSettingsParams{
+			UserID:        user.ID,
+			FeedID:        feed.ID,
+			TitleOverride: follow.TitleOverride,
+			Priority:      follow.Priority,
+			Muted:         follow.Muted,
+			Notify:        follow.Notify,
+		}
+		for _, arg := range cmd.Args[1:] {
+			key, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				return fmt.Errorf("invalid setting '%s', expected key=value", arg)
+			}
+			switch key {
+			case "title":
+				params.TitleOverride = nullString(value)
+				if value == "default" {
+					params.TitleOverride = sql.NullString{}
+				}
+			case "priority":
+				priority, err := strconv.ParseInt(value, 10, 32)
+				if err != nil {
+					return fmt.Errorf("invalid priority '%s'", value)
+				}
+				params.Priority = int32(priority)
+			case "muted", "notify":
+				enabled, err := strconv.ParseBool(value)
+				if err != nil {
+					return fmt.Errorf("invalid %s '%s', use true or false", key, value)
+				}
+				if key == "muted" {
+					params.Muted = enabled
+				} else {
+					params.Notify = enabled
+				}
+			default:
+				return errors.New(followSetUsage)
+			}
+		}
+
+		follow, err = s.DB.UpdateFollowSettings(context.Background(), params)
+		if err != nil {
+			return fmt.Errorf("failed to update follow: %w", err)
+		}
+	}
+
+	title := feed.Name
+	if follow.TitleOverride.Valid {
+		title = fmt.Sprintf("%s (feed name: %s)", follow.TitleOverride.String, feed.Name)
+	}
+	fmt.Printf("Title: %s\n", title)
+	fmt.Printf("Priority: %d\n", follow.Priority)
+	fmt.Printf("Muted: %t\n", follow.Muted)
+	fmt.Printf("Notify on new posts: %t\n", follow.Notify)
+	return nil
+}
+
 func HandlerAgg(s *State, cmd Command) error {
 	if err := validateArgs(cmd.Args, 1, "agg"); err != nil {
 		return err
@@ -467,8 +892,6 @@ func HandlerAgg(s *State, cmd Command) error {
 	for ; ; <-ticker.C {
 		scrapeFeeds(s)
 	}
-
-	return nil
 }
 
 func scrapeFeeds(s *State) {
@@ -486,43 +909,54 @@ func scrapeFeeds(s *State) {
 	fmt.Printf("\nFetching feed: %s (%s)\n", feed.Name, feed.Url)
 
 	// 2. Получить и обработать фид
-	rssFeed, err := fetchFeed(context.Background(), feed.Url)
+	rssFeed, err := fetchFeed(context.Background(), s.Fetcher, feed.Url)
 	if err != nil {
+		var retryErr *retryAfterError
+		if errors.As(err, &retryErr) {
+			// Издатель просит подождать - откладываем следующий фетч
+			fmt.Printf("Feed %s asked us to back off until %s\n", feed.Url, retryErr.RetryAt.Format("2006-01-02 15:04"))
+			if err := s.DB.DeferFeedFetch(context.Background(), database.DeferFeedFetchParams{
+				ID:             feed.ID,
+				NextFetchAt:    sql.NullTime{Time: retryErr.RetryAt, Valid: true},
+				LastFetchError: sql.NullString{String: retryErr.Error(), Valid: true},
+			}); err != nil {
+				fmt.Printf("Error deferring feed: %v\n", err)
+			}
+			return
+		}
+		if errors.Is(err, errFeedGone) {
+			fmt.Printf("Feed %s is gone, deactivating it\n", feed.Url)
+			if err := deactivateFeed(s, feed); err != nil {
+				fmt.Printf("Error deactivating feed: %v\n", err)
+			}
+			return
+		}
 		fmt.Printf("Error fetching feed %s: %v\n", feed.Url, err)
 		return
 	}
 
-	// 3. Вывести элементы
-	for _, item := range rssFeed.Channel.Item {
-		// fmt.Printf("%d. %s\n", i+1, item.Title)
-		// Парсим дату публикации (с обработкой разных форматов)
-        publishedAt, err := parseFeedDate(item.PubDate)
-        if err != nil {
-            fmt.Printf("Error parsing date '%s': %v\n", item.PubDate, err)
-            publishedAt = time.Now() // Используем текущее время как fallback
-        }
+	// Фид переехал навсегда - запоминаем новый URL
+	if rssFeed.MovedTo != "" {
+		fmt.Printf("Feed %s moved permanently to %s\n", feed.Url, rssFeed.MovedTo)
+		movedFeed, err := moveFeed(s, feed, rssFeed.MovedTo)
+		if err != nil {
+			fmt.Printf("Error updating feed url: %v\n", err)
+		} else {
+			feed = movedFeed
+		}
+	}
 
-		// Создаем параметры для сохранения поста
-		_, err = s.DB.CreatePost(context.Background(), database.CreatePostParams{
-			ID:          uuid.New(),
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-			Title:       item.Title,
-			Url:         item.Link,
-			Description: sql.NullString{String: item.Description, Valid: item.Description != ""},
-			PublishedAt: sql.NullTime{Time:  publishedAt, Valid: !publishedAt.IsZero()},
-			FeedID:      feed.ID,
-		})
+	// Канал мог сменить название, описание или иконку
+	metadata := feedMetadata(rssFeed)
+	metadata.ID = feed.ID
+	if err := s.DB.UpdateFeedMetadata(context.Background(), metadata); err != nil {
+		fmt.Printf("Error updating feed metadata: %v\n", err)
+	}
 
-		// Обрабатываем ошибки
-		if err != nil {
-			if strings.Contains(err.Error(), "duplicate key") {
-				// Пост уже существует - пропускаем
-				continue
-			}
+	// 3. Сохранить новые и изменённые посты
+	for _, item := range rssFeed.Channel.Item {
+		if err := savePost(s, feed, item); err != nil {
 			fmt.Printf("Error saving post '%s': %v\n", item.Title, err)
-		} else {
-			fmt.Printf("Saved post: %s\n", item.Title)
 		}
 	}
 
@@ -531,62 +965,375 @@ func scrapeFeeds(s *State) {
 	if err != nil {
 		fmt.Printf("Error marking feed as fetched: %v\n", err)
 	}
+
+	// 5. Удалить посты, вышедшие за пределы хранения
+	pruned, err := prunePosts(s, uuid.NullUUID{UUID: feed.ID, Valid: true}, false)
+	if err != nil {
+		fmt.Printf("Error pruning posts: %v\n", err)
+	} else if len(pruned) > 0 {
+		fmt.Printf("Pruned %d old posts\n", len(pruned))
+	}
 }
 
-func parseFeedDate(dateStr string) (time.Time, error) {
-    formats := []string{
-        time.RFC1123,
-        time.RFC1123Z,
-        time.RFC822,
-        time.RFC822Z,
-        time.RFC3339,
-        "Mon, 2 Jan 2006 15:04:05 -0700",
-        "2006-01-02T15:04:05Z",
-    }
+// moveFeed points feed at newURL. If another feed already uses newURL, the two
+// are merged: follows and posts move to the existing feed and feed is deleted.
+func moveFeed(s *State, feed database.Feed, newURL string) (database.Feed, error) {
+	ctx := context.Background()
 
-    for _, format := range formats {
-        t, err := time.Parse(format, dateStr)
-        if err == nil {
-            return t, nil
-        }
-    }
-    
-    return time.Time{}, fmt.Errorf("unrecognized date format: %s", dateStr)
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return feed, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.DB.WithTx(tx)
+
+	newURL = normalizeURL(newURL)
+	existing, err := getFeedByURL(ctx, qtx, newURL)
+	// The new URL may be a different spelling of the current one, https instead of http
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && existing.ID == feed.ID) {
+		moved, err := qtx.UpdateFeedURL(ctx, database.UpdateFeedURLParams{
+			ID:     feed.ID,
+			Url:    newURL,
+			UrlKey: urlKeyParam(newURL),
+		})
+		if err != nil {
+			return feed, fmt.Errorf("update feed url: %w", err)
+		}
+		return moved, tx.Commit()
+	}
+	if err != nil {
+		return feed, fmt.Errorf("database error: %w", err)
+	}
+
+	if err := mergeFeeds(ctx, qtx, feed, existing); err != nil {
+		return feed, err
+	}
+
+	fmt.Printf("Merged feed '%s' into existing feed '%s'\n", feed.Name, existing.Name)
+	return existing, tx.Commit()
+}
+
+// mergeFeeds moves the follows and posts of source to target and deletes source
+func mergeFeeds(ctx context.Context, qtx *database.Queries, source, target database.Feed) error {
+	merge := database.MergeFeedFollowsParams{
+		TargetFeedID: target.ID,
+		SourceFeedID: source.ID,
+	}
+	if err := qtx.MergeFeedFollows(ctx, merge); err != nil {
+		return fmt.Errorf("merge feed follows: %w", err)
+	}
+	// Posts the target already has are deleted with the source feed, so
+	// users' reads, stars and tags move to the target's copy first
+	conflicts, err := qtx.GetConflictingFeedPosts(ctx, database.GetConflictingFeedPostsParams(merge))
+	if err != nil {
+		return fmt.Errorf("find duplicate posts: %w", err)
+	}
+	for _, conflict := range conflicts {
+		if err := movePostState(ctx, qtx, conflict.KeeperID, conflict.DuplicateID); err != nil {
+			return err
+		}
+	}
+	if err := qtx.MergeFeedPosts(ctx, database.MergeFeedPostsParams(merge)); err != nil {
+		return fmt.Errorf("merge posts: %w", err)
+	}
+	if err := qtx.DeleteFeed(ctx, source.ID); err != nil {
+		return fmt.Errorf("delete old feed: %w", err)
+	}
+	// The target may have gained its first followers
+	if err := qtx.MarkOrphanedFeeds(ctx); err != nil {
+		return fmt.Errorf("update orphaned feeds: %w", err)
+	}
+	return nil
+}
+
+// movePostState gives keeper the reads, stars and tags users put on duplicate,
+// before duplicate is deleted
+func movePostState(ctx context.Context, qtx *database.Queries, keeper, duplicate uuid.UUID) error {
+	move := database.MovePostReadsParams{
+		KeeperID:    keeper,
+		DuplicateID: duplicate,
+	}
+	if err := qtx.MovePostReads(ctx, move); err != nil {
+		return fmt.Errorf("move read state: %w", err)
+	}
+	if err := qtx.MovePostStars(ctx, database.MovePostStarsParams(move)); err != nil {
+		return fmt.Errorf("move stars: %w", err)
+	}
+	if err := qtx.MovePostTags(ctx, database.MovePostTagsParams(move)); err != nil {
+		return fmt.Errorf("move tags: %w", err)
+	}
+	return nil
+}
+
+// deactivateFeed takes a feed out of the fetch rotation and tells its followers why.
+func deactivateFeed(s *State, feed database.Feed) error {
+	ctx := context.Background()
+
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.DB.WithTx(tx)
+
+	if err := qtx.DeactivateFeed(ctx, database.DeactivateFeedParams{
+		ID:             feed.ID,
+		LastFetchError: sql.NullString{String: errFeedGone.Error(), Valid: true},
+	}); err != nil {
+		return fmt.Errorf("deactivate feed: %w", err)
+	}
+
+	if err := qtx.NotifyFeedFollowers(ctx, database.NotifyFeedFollowersParams{
+		FeedID:  feed.ID,
+		Message: fmt.Sprintf("Feed '%s' (%s) is gone and will no longer be fetched", feed.Name, feed.Url),
+	}); err != nil {
+		return fmt.Errorf("notify followers: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// postGUID identifies an item within its feed: the publisher's guid (or Atom id),
+// else its link, else a hash of the content for items that have neither.
+func postGUID(item RSSItem) string {
+	if guid := strings.TrimSpace(item.GUID); guid != "" {
+		return guid
+	}
+	// The link as published, so resolving it differently later keeps the GUID
+	link := strings.TrimSpace(item.RawLink)
+	if link == "" {
+		link = strings.TrimSpace(item.Link)
+	}
+	if link != "" {
+		return link
+	}
+	sum := sha256.Sum256([]byte(item.Title + "\x00" + item.Description + "\x00" + item.PubDate))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// browseOptions are the arguments of the browse command:
+// browse [limit] [--full] [--author <name>] [--category <name>] [--folder <name>] [--tag <tag>]
+type browseOptions struct {
+	Limit    int32
+	Full     bool
+	Author   string
+	Category string
+	Folder   string
+	Tag      string
+	// Query is set by the search command
+	Query string
+
+	// Starred and Tagged list the user's starred or tagged posts instead of
+	// their feeds, so these stay listed after an unfollow or mute
+	Starred bool
+	Tagged  bool
+}
+
+func parseBrowseArgs(args []string, defaultLimit int32) (browseOptions, error) {
+	opts := browseOptions{Limit: defaultLimit}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		value := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("%s requires a value", arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		var err error
+		switch arg {
+		case "--full":
+			opts.Full = true
+		case "--author":
+			opts.Author, err = value()
+		case "--category":
+			opts.Category, err = value()
+		case "--folder":
+			opts.Folder, err = value()
+		case "--tag":
+			opts.Tag, err = value()
+		default:
+			limit, parseErr := strconv.ParseInt(arg, 10, 32)
+			if parseErr != nil {
+				return opts, fmt.Errorf("invalid limit value: %w", parseErr)
+			}
+			opts.Limit = int32(limit)
+		}
+		if err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
 }
 
 func HandlerBrowse(s *State, cmd Command, user database.User) error {
-	limitPost := int32(2);
+	opts, err := parseBrowseArgs(cmd.Args, 2)
+	if err != nil {
+		return err
+	}
+	return showPosts(s, user, opts)
+}
 
-	if len(cmd.Args) == 1 {
-        limit, err := strconv.ParseInt(cmd.Args[0], 10, 32)
-        if err != nil {
-            return fmt.Errorf("invalid limit value: %w", err)
-        }
-        limitPost = int32(limit)
+// getPosts returns the user's posts matching the browse filters. The starred
+// and tagged queries return the same columns as GetPostsForUser.
+func getPosts(s *State, user database.User, opts browseOptions) ([]database.GetPostsForUserRow, error) {
+	folderID, err := folderFilter(s, user, opts.Folder)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case opts.Starred:
+		rows, err := s.DB.GetStarredPostsForUser(context.Background(), database.GetStarredPostsForUserParams{
+			UserID:   user.ID,
+			Limit:    opts.Limit,
+			FolderID: folderID,
+			Author:   nullString(opts.Author),
+			Category: nullString(opts.Category),
+			Tag:      nullString(opts.Tag),
+			Query:    nullString(opts.Query),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get starred posts: %w", err)
+		}
+		posts := make([]database.GetPostsForUserRow, len(rows))
+		for i, row := range rows {
+			posts[i] = database.GetPostsForUserRow(row)
+		}
+		return posts, nil
+
+	case opts.Tagged || opts.Tag != "":
+		rows, err := s.DB.GetTaggedPostsForUser(context.Background(), database.GetTaggedPostsForUserParams{
+			UserID:   user.ID,
+			Limit:    opts.Limit,
+			FolderID: folderID,
+			Author:   nullString(opts.Author),
+			Category: nullString(opts.Category),
+			Tag:      nullString(opts.Tag),
+			Query:    nullString(opts.Query),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tagged posts: %w", err)
+		}
+		posts := make([]database.GetPostsForUserRow, len(rows))
+		for i, row := range rows {
+			posts[i] = database.GetPostsForUserRow(row)
+		}
+		return posts, nil
 	}
-	
+
 	posts, err := s.DB.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
-		UserID:	user.ID,
-		Limit:	limitPost,
+		UserID:		user.ID,
+		Limit:		opts.Limit,
+		FolderID:	folderID,
+		Author:		nullString(opts.Author),
+		Category:	nullString(opts.Category),
+		Query:		nullString(opts.Query),
 	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts: %w", err)
+	}
+	return posts, nil
+}
 
+func showPosts(s *State, user database.User, opts browseOptions) error {
+	posts, err := getPostClusters(s, user, opts)
 	if err != nil {
-		return fmt.Errorf("failed to get posts: %w", err)
+		return err
+	}
+	if len(posts) == 0 {
+		fmt.Println("No posts found")
+		return nil
 	}
 
 	for i, post := range posts {
 		fmt.Printf("\n=== Post %d ===\n", i+1)
+		fmt.Printf("ID: %s\n", post.ID)
 		fmt.Printf("Title: %s\n", post.Title)
-		fmt.Printf("URL: %s\n", post.Url)
+		if post.Url != "" {
+			fmt.Printf("URL: %s\n", post.Url)
+		}
+		if post.Author.Valid {
+			fmt.Printf("Author: %s\n", post.Author.String)
+		}
+		if post.Categories != "" {
+			fmt.Printf("Categories: %s\n", post.Categories)
+		}
+		if post.Tags != "" {
+			fmt.Printf("Tags: %s\n", post.Tags)
+		}
 
-		// Decode HTML entities in text fields
-		plainDesc := html.UnescapeString(post.Description.String)
+		text := post.Description.String
+		if opts.Full && post.Content.Valid {
+			text = post.Content.String
+		}
+		// Render the HTML as wrapped text with link footnotes
+		plainDesc := renderPlainText(text, plainTextWidth)
 		fmt.Printf("Description:\n%s\n", plainDesc)
 
 		fmt.Printf("Published: %s\n", post.PublishedAt.Time.Format("2006-01-02 15:04"))
 		fmt.Printf("Feed: %s\n", post.FeedName)
+		if len(post.AlsoIn) > 0 {
+			fmt.Printf("Also in: %s\n", strings.Join(post.AlsoIn, ", "))
+		}
+		if post.CommentsUrl.Valid {
+			fmt.Printf("Comments: %s\n", post.CommentsUrl.String)
+		}
+
+		attachments, err := s.DB.GetAttachmentsForPost(context.Background(), post.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get attachments: %w", err)
+		}
+		for _, attachment := range attachments {
+			fmt.Printf("Attachment: %s\n", formatAttachment(attachment))
+		}
+		if post.UpdatedAt.After(post.CreatedAt.Add(time.Minute)) {
+			fmt.Printf("Updated: %s\n", post.UpdatedAt.Format("2006-01-02 15:04"))
+		}
+		if post.IsStarred {
+			fmt.Println("Starred")
+		}
+		if post.Snapshot.Valid {
+			if dir, err := archiveDir(s.Config); err == nil {
+				fmt.Printf("Snapshot: %s\n", filepath.Join(dir, filepath.FromSlash(post.Snapshot.String)))
+			}
+		}
+		if post.IsRead {
+			fmt.Println("Status: read")
+		}
 		fmt.Println("------------------------")
 	}
 
+	return nil
+}
+
+func HandlerNotifications(s *State, cmd Command, user database.User) error {
+	if err := validateArgs(cmd.Args, 0, "notifications"); err != nil {
+		return err
+	}
+
+	notifications, err := s.DB.GetUnreadNotifications(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get notifications: %w", err)
+	}
+
+	if len(notifications) == 0 {
+		fmt.Println("No new notifications")
+		return nil
+	}
+
+	for _, notification := range notifications {
+		fmt.Printf("* [%s] %s\n", notification.CreatedAt.Format("2006-01-02 15:04"), notification.Message)
+		if notification.PostID.Valid {
+			fmt.Printf("  gator view %s\n", notification.PostID.UUID)
+		}
+	}
+
+	if err := s.DB.MarkNotificationsRead(context.Background(), user.ID); err != nil {
+		return fmt.Errorf("failed to mark notifications as read: %w", err)
+	}
+
 	return nil
 }
\ No newline at end of file
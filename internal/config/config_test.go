@@ -0,0 +1,77 @@
+package config
+
+import "testing"
+
+func TestDecodeXML(t *testing.T) {
+	// "Привет" in windows-1251 and "café" in ISO-8859-1
+	cp1251 := "\xcf\xf0\xe8\xe2\xe5\xf2"
+	latin1 := "caf\xe9"
+
+	tests := []struct {
+		name        string
+		data        string
+		contentType string
+		want        string
+	}{
+		{
+			name: "UTF-8 without a charset",
+			data: `<?xml version="1.0" encoding="UTF-8"?><title>Привет</title>`,
+			want: "Привет",
+		},
+		{
+			name: "charset from the declaration",
+			data: `<?xml version="1.0" encoding="windows-1251"?><title>` + cp1251 + `</title>`,
+			want: "Привет",
+		},
+		{
+			name:        "charset from the declaration with a generic header",
+			data:        `<?xml version="1.0" encoding="windows-1251"?><title>` + cp1251 + `</title>`,
+			contentType: "application/rss+xml",
+			want:        "Привет",
+		},
+		{
+			name:        "header charset wins over the declaration",
+			data:        `<?xml version="1.0" encoding="UTF-8"?><title>` + latin1 + `</title>`,
+			contentType: "application/xml; charset=ISO-8859-1",
+			want:        "café",
+		},
+		{
+			name:        "header without a declaration",
+			data:        `<title>` + cp1251 + `</title>`,
+			contentType: "text/xml; charset=windows-1251",
+			want:        "Привет",
+		},
+		{
+			name:        "UTF-8 header on bytes that are not UTF-8",
+			data:        `<?xml version="1.0" encoding="windows-1251"?><title>` + cp1251 + `</title>`,
+			contentType: "application/xml; charset=utf-8",
+			want:        "Привет",
+		},
+		{
+			name:        "UTF-8 header overrides a wrong declaration",
+			data:        `<?xml version="1.0" encoding="ISO-8859-1"?><title>Привет</title>`,
+			contentType: "application/xml; charset=UTF-8",
+			want:        "Привет",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var title string
+			if err := decodeXML([]byte(tt.data), tt.contentType, &title); err != nil {
+				t.Fatalf("decodeXML returned error: %v", err)
+			}
+			if title != tt.want {
+				t.Errorf("decodeXML = %q, want %q", title, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeXMLUnsupportedCharset(t *testing.T) {
+	var title string
+	err := decodeXML([]byte(`<title>x</title>`), "text/xml; charset=x-no-such-charset", &title)
+	if err == nil {
+		t.Fatal("decodeXML accepted an unknown charset")
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxFutureSkew is how far ahead of our clock a publication date may be
+// before we treat it as bogus and clamp it
+const maxFutureSkew = 15 * time.Minute
+
+// dateLayouts are tried in order after normalizeFeedDate. Day names are
+// stripped beforehand and named zones are replaced by numeric offsets.
+var dateLayouts = []string{
+	// RFC 822 / 1123 and the many ways feeds get them wrong
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 -07:00",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 January 2006 15:04:05",
+	"2 Jan 2006",
+	"2 January 2006",
+	// ISO 8601 / RFC 3339 variants
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05.999999999-0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"20060102T150405Z07:00",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	// "January 2, 2006" style
+	"January 2, 2006 15:04:05 -0700",
+	"January 2, 2006 15:04 -0700",
+	"January 2, 2006",
+	"Jan 2, 2006 15:04:05 -0700",
+	"Jan 2, 2006",
+	// ANSI C / Unix date
+	"Jan 2 15:04:05 2006",
+	"Jan 2 15:04:05 -0700 2006",
+}
+
+// namedZones maps the zone abbreviations seen in feeds to their offsets.
+// time.Parse only knows abbreviations of the local zone and silently
+// treats any other one as UTC.
+var namedZones = map[string]string{
+	"UT": "+0000", "UTC": "+0000", "GMT": "+0000", "Z": "+0000", "WET": "+0000",
+	"BST": "+0100", "CET": "+0100", "WEST": "+0100", "IST": "+0530",
+	"CEST": "+0200", "EET": "+0200", "EEST": "+0300", "MSK": "+0300",
+	"JST": "+0900", "KST": "+0900", "HKT": "+0800", "SGT": "+0800", "AWST": "+0800",
+	"AEST": "+1000", "AEDT": "+1100", "NZST": "+1200", "NZDT": "+1300",
+	"EST": "-0500", "EDT": "-0400", "CST": "-0600", "CDT": "-0500",
+	"MST": "-0700", "MDT": "-0600", "PST": "-0800", "PDT": "-0700",
+	"AKST": "-0900", "AKDT": "-0800", "HST": "-1000",
+	// RFC 822 military zones besides Z are too often wrong to trust
+}
+
+var (
+	// "Mon, ", "Tues ", "Thursday, " - weekday names are often misspelled and never needed
+	weekdayPrefix = regexp.MustCompile(`(?i)^(?:mon|tue|wed|thu|fri|sat|sun)[a-z]*\.?,?\s+`)
+	// "+0000 (UTC)" - a comment repeating the numeric offset
+	zoneComment = regexp.MustCompile(`([+-]\d{2}:?\d{2})\s*\([A-Za-z]+\)$`)
+	// a trailing zone name
+	zoneSuffix = regexp.MustCompile(`\s+\(?([A-Za-z]{1,5})\)?$`)
+	// "GMT+3", "UTC-05:00"
+	zoneOffsetSuffix = regexp.MustCompile(`\s*(?:GMT|UTC)([+-])(\d{1,2})(?::?(\d{2}))?$`)
+	whitespace       = regexp.MustCompile(`\s+`)
+	// "Jan 2, 2006" must keep its comma, "2 Jan, 2006" needs it removed
+	commaAfterMonth = regexp.MustCompile(`^(\d{1,2} [A-Za-z]+),`)
+)
+
+// normalizeFeedDate removes the variations the layouts do not cover
+func normalizeFeedDate(dateStr string) string {
+	value := whitespace.ReplaceAllString(strings.TrimSpace(dateStr), " ")
+
+	value = weekdayPrefix.ReplaceAllString(value, "")
+	value = zoneComment.ReplaceAllString(value, "$1")
+	value = commaAfterMonth.ReplaceAllString(value, "$1")
+
+	if match := zoneOffsetSuffix.FindStringSubmatch(value); match != nil {
+		minutes := match[3]
+		if minutes == "" {
+			minutes = "00"
+		}
+		offset := fmt.Sprintf("%s%02s%s", match[1], match[2], minutes)
+		value = strings.TrimSpace(value[:len(value)-len(match[0])]) + " " + offset
+	} else if match := zoneSuffix.FindStringSubmatch(value); match != nil {
+		if offset, ok := namedZones[strings.ToUpper(match[1])]; ok {
+			value = value[:len(value)-len(match[0])] + " " + offset
+		}
+	}
+	return value
+}
+
+// parseFeedDate understands the date formats found in RSS, Atom and JSON feeds.
+// Dates without a zone are taken as UTC.
+func parseFeedDate(dateStr string) (time.Time, error) {
+	if strings.TrimSpace(dateStr) == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+
+	value := normalizeFeedDate(dateStr)
+	for _, layout := range dateLayouts {
+		t, err := time.ParseInLocation(layout, value, time.UTC)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date format: %s", dateStr)
+}
+
+// postPublishedAt decides the publication date to store. Unparseable dates
+// fall back to when we first saw the post, so they keep their place in browse
+// across scrapes, and dates in the future are clamped to now.
+func postPublishedAt(dateStr string, firstSeen time.Time) time.Time {
+	publishedAt, err := parseFeedDate(dateStr)
+	if err != nil {
+		return firstSeen
+	}
+
+	now := time.Now()
+	if publishedAt.After(now.Add(maxFutureSkew)) {
+		return now
+	}
+	return publishedAt
+}
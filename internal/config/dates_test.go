@@ -0,0 +1,143 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFeedDate(t *testing.T) {
+	want := time.Date(2024, time.March, 5, 14, 30, 0, 0, time.UTC)
+	midnight := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		// RFC 822 / 1123
+		{"Tue, 05 Mar 2024 14:30:00 +0000", want},
+		{"Tue, 05 Mar 2024 14:30:00 GMT", want},
+		{"Tue, 05 Mar 2024 09:30:00 EST", want},
+		{"Tue, 05 Mar 2024 06:30:00 PST", want},
+		{"Tue, 05 Mar 2024 15:30:00 CET", want},
+		{"Tue, 05 Mar 2024 23:30:00 JST", want},
+		{"Tue, 05 Mar 2024 14:30:00 UT", want},
+		{"Tue, 05 Mar 2024 14:30:00 Z", want},
+		{"Tue, 05 Mar 2024 14:30:00 est", time.Date(2024, time.March, 5, 19, 30, 0, 0, time.UTC)},
+		{"Tue, 05 Mar 2024 16:30:00 +02:00", want},
+		{"Tue, 05 Mar 2024 14:30 +0000", want},
+		{"Tue, 05 Mar 24 14:30:00 +0000", want},
+		{"Tue, 5 Mar 2024 14:30:00 +0000", want},
+		{"05 Mar 2024 14:30:00 +0000", want},
+		{"Tue, 05 March 2024 14:30:00 +0000", want},
+		{"Tue, 05 Mar 2024 14:30:00", want},
+		{"Tue, 05 Mar 2024", midnight},
+		// Sloppy weekdays, commas, whitespace and zone comments
+		{"Tues, 05 Mar 2024 14:30:00 +0000", want},
+		{"Tuesday, 05 Mar 2024 14:30:00 +0000", want},
+		{"Tue 05 Mar 2024 14:30:00 +0000", want},
+		{"Mon, 05 Mar 2024 14:30:00 +0000", want},
+		{"  Tue,  05 Mar 2024\n14:30:00  +0000 ", want},
+		{"05 Mar, 2024 14:30:00 +0000", want},
+		{"Tue, 05 Mar 2024 14:30:00 +0000 (UTC)", want},
+		{"Tue, 05 Mar 2024 14:30:00 (GMT)", want},
+		{"Tue, 05 Mar 2024 17:30:00 GMT+3", want},
+		{"Tue, 05 Mar 2024 09:30:00 UTC-05:00", want},
+		{"Tue, 05 Mar 2024 20:00:00 GMT+0530", want},
+		// ISO 8601 / RFC 3339
+		{"2024-03-05T14:30:00Z", want},
+		{"2024-03-05T14:30:00.123Z", want.Add(123 * time.Millisecond)},
+		{"2024-03-05T16:30:00+02:00", want},
+		{"2024-03-05T16:30:00+0200", want},
+		{"2024-03-05T16:30:00.5+0200", want.Add(500 * time.Millisecond)},
+		{"2024-03-05T14:30Z", want},
+		{"2024-03-05T14:30:00", want},
+		{"2024-03-05T14:30", want},
+		{"2024-03-05 14:30:00Z", want},
+		{"2024-03-05 16:30:00 +0200", want},
+		{"2024-03-05 16:30:00 +02:00", want},
+		{"2024-03-05 14:30:00", want},
+		{"2024-03-05 14:30", want},
+		{"20240305T143000Z", want},
+		{"2024-03-05", midnight},
+		{"2024/03/05 14:30:00", want},
+		{"2024/03/05", midnight},
+		// "January 2, 2006"
+		{"March 5, 2024 14:30:00 +0000", want},
+		{"March 5, 2024 14:30 +0000", want},
+		{"March 5, 2024", midnight},
+		{"Mar 5, 2024 14:30:00 +0000", want},
+		{"Mar 5, 2024", midnight},
+		// ANSI C / Unix date
+		{"Tue Mar 5 14:30:00 2024", want},
+		{"Tue Mar  5 14:30:00 2024", want},
+		{"Tue Mar 5 14:30:00 +0000 2024", want},
+	}
+
+	for _, tt := range tests {
+		got, err := parseFeedDate(tt.in)
+		if err != nil {
+			t.Errorf("parseFeedDate(%q) returned error: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseFeedDate(%q) = %v, want %v", tt.in, got.UTC(), tt.want)
+		}
+	}
+}
+
+func TestParseFeedDateInvalid(t *testing.T) {
+	for _, in := range []string{"", "   ", "yesterday", "not a date", "2024-13-45", "32 Mar 2024"} {
+		if got, err := parseFeedDate(in); err == nil {
+			t.Errorf("parseFeedDate(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestNormalizeFeedDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Tue, 05 Mar 2024 14:30:00 GMT", "05 Mar 2024 14:30:00 +0000"},
+		{"Thursday, 07 Mar 2024 14:30:00 PDT", "07 Mar 2024 14:30:00 -0700"},
+		{"Tue, 05 Mar 2024 14:30:00 +0000 (UTC)", "05 Mar 2024 14:30:00 +0000"},
+		{"Tue, 05 Mar 2024 14:30:00 GMT+3", "05 Mar 2024 14:30:00 +0300"},
+		{"Tue, 05 Mar 2024 14:30:00 UTC-05:30", "05 Mar 2024 14:30:00 -0530"},
+		{"5 Mar, 2024", "5 Mar 2024"},
+		{"Mar 5, 2024", "Mar 5, 2024"},
+		// Unknown zone names are left for the layouts to reject
+		{"05 Mar 2024 14:30:00 XYZ", "05 Mar 2024 14:30:00 XYZ"},
+		{"2024-03-05T14:30:00Z", "2024-03-05T14:30:00Z"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeFeedDate(tt.in); got != tt.want {
+			t.Errorf("normalizeFeedDate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPostPublishedAt(t *testing.T) {
+	firstSeen := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+
+	if got := postPublishedAt("garbage", firstSeen); !got.Equal(firstSeen) {
+		t.Errorf("unparseable date = %v, want first seen %v", got, firstSeen)
+	}
+
+	published := "2024-02-28T10:00:00Z"
+	if got := postPublishedAt(published, firstSeen); !got.Equal(time.Date(2024, time.February, 28, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("postPublishedAt(%q) = %v", published, got)
+	}
+
+	nearFuture := time.Now().Add(5 * time.Minute).UTC().Truncate(time.Second)
+	if got := postPublishedAt(nearFuture.Format(time.RFC3339), firstSeen); !got.Equal(nearFuture) {
+		t.Errorf("date within the allowed skew = %v, want %v", got, nearFuture)
+	}
+
+	before := time.Now()
+	future := before.Add(48 * time.Hour).UTC().Format(time.RFC3339)
+	got := postPublishedAt(future, firstSeen)
+	if got.Before(before) || got.After(time.Now()) {
+		t.Errorf("future date %q = %v, want it clamped to now", future, got)
+	}
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/BabichevDima/aggregator/internal/database"
+)
+
+// Titles whose simhashes differ in more bits than this are different stories
+const maxTitleDistance = 12
+
+// Titles with a simhash match must also share this share of their words
+const minTitleOverlap = 0.75
+
+// Title similarity only counts for posts published this close together,
+// so recurring titles ("Weekly digest #12") don't merge
+const sameStoryWindow = 72 * time.Hour
+
+// titleStopWords carry no meaning for comparing titles
+var titleStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "is": true, "are": true, "to": true, "of": true,
+	"in": true, "on": true, "for": true, "with": true, "and": true, "or": true, "at": true,
+}
+
+// ampParams select the AMP version of a page
+var ampParams = []string{"amp", "outputType"}
+
+// canonicalPostURL reduces a post URL to what identifies the story: its
+// urlKey, with AMP and mobile variants mapped to the regular page.
+func canonicalPostURL(raw string) string {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Host == "" {
+		return urlKey(raw)
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	host = strings.TrimPrefix(host, "amp.")
+	host = strings.TrimPrefix(host, "m.")
+	parsed.Host = host
+
+	urlPath := strings.TrimSuffix(parsed.Path, "/")
+	urlPath = strings.TrimSuffix(urlPath, "/amp")
+	urlPath = strings.TrimSuffix(urlPath, ".amp")
+	if strings.HasPrefix(urlPath, "/amp/") {
+		urlPath = strings.TrimPrefix(urlPath, "/amp")
+	}
+	parsed.Path = urlPath
+	parsed.RawPath = ""
+
+	query := parsed.Query()
+	for _, key := range ampParams {
+		query.Del(key)
+	}
+	parsed.RawQuery = query.Encode()
+
+	return urlKey(parsed.String())
+}
+
+func titleWords(title string) []string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words = slices.DeleteFunc(words, func(word string) bool { return titleStopWords[word] })
+	slices.Sort(words)
+	return slices.Compact(words)
+}
+
+// titleSimhash fingerprints a title so that titles sharing most words get
+// fingerprints differing in only a few bits
+func titleSimhash(words []string) uint64 {
+	var weights [64]int
+	for _, word := range words {
+		h := fnv.New64a()
+		h.Write([]byte(word))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var hash uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+// storyKey is what sameStory compares, computed once per post
+type storyKey struct {
+	url   string
+	words []string
+	hash  uint64
+}
+
+func newStoryKey(post database.GetPostsForUserRow) storyKey {
+	words := titleWords(post.Title)
+	key := storyKey{
+		words: words,
+		hash:  titleSimhash(words),
+	}
+	if post.Url != "" {
+		key.url = canonicalPostURL(post.Url)
+	}
+	return key
+}
+
+// similarTitles uses the simhash as a cheap first check, then compares the words
+func similarTitles(a, b storyKey) bool {
+	if len(a.words) < 3 || len(b.words) < 3 {
+		return false
+	}
+	if bits.OnesCount64(a.hash^b.hash) > maxTitleDistance {
+		return false
+	}
+
+	shared := 0
+	for _, word := range a.words {
+		if _, found := slices.BinarySearch(b.words, word); found {
+			shared++
+		}
+	}
+	union := len(a.words) + len(b.words) - shared
+	return float64(shared)/float64(union) >= minTitleOverlap
+}
+
+// postCluster is a post shown in browse together with the other feeds that
+// carried the same story
+type postCluster struct {
+	database.GetPostsForUserRow
+	AlsoIn []string
+
+	key storyKey
+}
+
+func postTime(post database.GetPostsForUserRow) time.Time {
+	if post.PublishedAt.Valid {
+		return post.PublishedAt.Time
+	}
+	return post.CreatedAt
+}
+
+func sameStory(a postCluster, b database.GetPostsForUserRow, bKey storyKey) bool {
+	if a.key.url != "" && a.key.url == bKey.url {
+		return true
+	}
+	if a.FeedID == b.FeedID {
+		return false
+	}
+	gap := postTime(a.GetPostsForUserRow).Sub(postTime(b)).Abs()
+	return gap <= sameStoryWindow && similarTitles(a.key, bKey)
+}
+
+// clusterPosts folds posts of the same story into the first of them, keeping order
+func clusterPosts(posts []database.GetPostsForUserRow) []postCluster {
+	var clusters []postCluster
+	for _, post := range posts {
+		key := newStoryKey(post)
+		merged := false
+		for i := range clusters {
+			if !sameStory(clusters[i], post, key) {
+				continue
+			}
+			if post.FeedName != clusters[i].FeedName && !slices.Contains(clusters[i].AlsoIn, post.FeedName) {
+				clusters[i].AlsoIn = append(clusters[i].AlsoIn, post.FeedName)
+			}
+			merged = true
+			break
+		}
+		if !merged {
+			clusters = append(clusters, postCluster{GetPostsForUserRow: post, key: key})
+		}
+	}
+	return clusters
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/BabichevDima/aggregator/internal/database"
+)
+
+func TestCanonicalPostURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://example.com/news/story", "example.com/news/story"},
+		{"https://www.example.com/news/story/?utm_source=rss", "example.com/news/story"},
+		// AMP and mobile variants
+		{"https://amp.example.com/news/story", "example.com/news/story"},
+		{"https://m.example.com/news/story", "example.com/news/story"},
+		{"https://example.com/news/story/amp", "example.com/news/story"},
+		{"https://example.com/news/story/amp/", "example.com/news/story"},
+		{"https://example.com/news/story.amp", "example.com/news/story"},
+		{"https://example.com/amp/news/story", "example.com/news/story"},
+		{"https://example.com/news/story?amp=1", "example.com/news/story"},
+		{"https://example.com/news/story?outputType=amp&id=3", "example.com/news/story?id=3"},
+		// Only a whole /amp segment is an AMP marker
+		{"https://example.com/news/camp", "example.com/news/camp"},
+		{"https://example.com/amplifier", "example.com/amplifier"},
+		// Relative links fall back to urlKey
+		{"/news/story", "/news/story"},
+	}
+
+	for _, tt := range tests {
+		if got := canonicalPostURL(tt.in); got != tt.want {
+			t.Errorf("canonicalPostURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTitleWords(t *testing.T) {
+	got := titleWords("The Rust 2.0 release is here, and the release is big!")
+	want := []string{"0", "2", "big", "here", "release", "rust"}
+	if len(got) != len(want) {
+		t.Fatalf("titleWords = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("titleWords = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestSimilarTitles(t *testing.T) {
+	key := func(title string) storyKey {
+		return newStoryKey(database.GetPostsForUserRow{Title: title})
+	}
+
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Go 1.24 released with generic type aliases", "Go 1.24 released with generic type aliases", true},
+		{"Go 1.24 released with generic type aliases", "Go 1.24 Released With Generic Type Aliases!", true},
+		{"Go 1.24 released with generic type aliases", "The Go 1.24 is released with generic type aliases", true},
+		{"Go 1.24 released with generic type aliases", "Rust 1.85 released with async closures", false},
+		{"Apple announces new MacBook Pro", "Google announces new Pixel phone", false},
+		// Too short to tell
+		{"Weekly digest", "Weekly digest", false},
+		{"The news", "The news", false},
+	}
+
+	for _, tt := range tests {
+		if got := similarTitles(key(tt.a), key(tt.b)); got != tt.want {
+			t.Errorf("similarTitles(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := similarTitles(key(tt.b), key(tt.a)); got != tt.want {
+			t.Errorf("similarTitles(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
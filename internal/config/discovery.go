@@ -0,0 +1,184 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// feedLinkTypes are the <link type="..."> values that point at a feed
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+// feedCandidate is a feed advertised by an HTML page
+type feedCandidate struct {
+	URL   string
+	Title string
+	Type  string
+}
+
+// htmlPageError is returned by fetchFeed when the URL serves an HTML page,
+// so callers can look for the feeds the page links to.
+type htmlPageError struct {
+	URL  string
+	Body []byte
+}
+
+func (e *htmlPageError) Error() string {
+	return fmt.Sprintf("%s is an HTML page, not a feed", e.URL)
+}
+
+func (e *htmlPageError) Unwrap() error {
+	return errNotAFeed
+}
+
+// discoverFeedLinks collects <link rel="alternate"> feed tags from the page head,
+// resolving relative hrefs against the page URL (or its <base href>).
+func discoverFeedLinks(page []byte, pageURL string) []feedCandidate {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []feedCandidate
+	seen := make(map[string]bool)
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return candidates
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			attrs := make(map[string]string)
+			for _, attr := range token.Attr {
+				attrs[strings.ToLower(attr.Key)] = strings.TrimSpace(attr.Val)
+			}
+
+			switch token.Data {
+			case "base":
+				if href, err := url.Parse(attrs["href"]); err == nil && attrs["href"] != "" {
+					base = base.ResolveReference(href)
+				}
+			case "link":
+				if !hasToken(attrs["rel"], "alternate") || !feedLinkTypes[strings.ToLower(attrs["type"])] {
+					continue
+				}
+				href, err := url.Parse(attrs["href"])
+				if err != nil || attrs["href"] == "" {
+					continue
+				}
+				feedURL := base.ResolveReference(href).String()
+				if seen[feedURL] {
+					continue
+				}
+				seen[feedURL] = true
+				candidates = append(candidates, feedCandidate{
+					URL:   feedURL,
+					Title: attrs["title"],
+					Type:  strings.ToLower(attrs["type"]),
+				})
+			case "body":
+				// feed links live in <head>
+				return candidates
+			}
+		}
+	}
+}
+
+func hasToken(list, token string) bool {
+	for _, field := range strings.Fields(list) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFeedURL fetches rawURL and returns the URL to subscribe to along with
+// the parsed feed. When rawURL is an HTML page, the feeds it advertises are
+// fetched to check they work and the user picks one if there are several.
+func resolveFeedURL(ctx context.Context, s *State, rawURL string) (string, *RSSFeed, error) {
+	rssFeed, err := fetchFeed(ctx, s.Fetcher, rawURL)
+	if err == nil {
+		if rssFeed.MovedTo != "" {
+			return rssFeed.MovedTo, rssFeed, nil
+		}
+		return rawURL, rssFeed, nil
+	}
+
+	var page *htmlPageError
+	if !errors.As(err, &page) {
+		return "", nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+
+	candidates := discoverFeedLinks(page.Body, page.URL)
+	if len(candidates) == 0 {
+		return "", nil, fmt.Errorf("%w and it does not link to any feeds", err)
+	}
+
+	var valid []feedCandidate
+	var feeds []*RSSFeed
+	for _, candidate := range candidates {
+		feed, err := fetchFeed(ctx, s.Fetcher, candidate.URL)
+		if err != nil {
+			fmt.Printf("Skipping %s: %v\n", candidate.URL, err)
+			continue
+		}
+		if feed.MovedTo != "" {
+			candidate.URL = feed.MovedTo
+		}
+		valid = append(valid, candidate)
+		feeds = append(feeds, feed)
+	}
+
+	switch len(valid) {
+	case 0:
+		return "", nil, fmt.Errorf("none of the feeds linked from %s could be fetched", page.URL)
+	case 1:
+		fmt.Printf("Discovered feed: %s\n", valid[0].URL)
+		return valid[0].URL, feeds[0], nil
+	}
+
+	choice, err := chooseFeedCandidate(valid)
+	if err != nil {
+		return "", nil, err
+	}
+	return valid[choice].URL, feeds[choice], nil
+}
+
+// chooseFeedCandidate lists the candidates and asks the user to pick one on stdin.
+func chooseFeedCandidate(candidates []feedCandidate) (int, error) {
+	fmt.Println("The page links to several feeds:")
+	for i, candidate := range candidates {
+		title := candidate.Title
+		if title == "" {
+			title = candidate.Type
+		}
+		fmt.Printf("%d. %s (%s)\n", i+1, title, candidate.URL)
+	}
+	fmt.Printf("Choose a feed [1-%d]: ", len(candidates))
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Println()
+		return 0, errors.New("several feeds found, run addfeed again with one of the URLs above")
+	}
+
+	choice, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || choice < 1 || choice > len(candidates) {
+		return 0, fmt.Errorf("invalid choice: %s", strings.TrimSpace(line))
+	}
+	return choice - 1, nil
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BabichevDima/aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+type opmlDocument struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Title   string        `xml:"head>title"`
+	Body    []opmlOutline `xml:"body>outline"`
+}
+
+// opmlOutline is a feed when XMLURL is set, otherwise a folder
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// subscriptionsOPML builds an OPML document of follows, folders becoming
+// outlines that contain their feeds
+func subscriptionsOPML(user database.User, follows []database.GetFeedFollowsForUserRow) opmlDocument {
+	doc := opmlDocument{
+		Version: "2.0",
+		Title:   fmt.Sprintf("%s's gator subscriptions", user.Name),
+	}
+
+	folders := make(map[string]int)
+	for _, follow := range follows {
+		feed := opmlOutline{
+			Text:    follow.FeedName,
+			Title:   follow.FeedName,
+			Type:    "rss",
+			XMLURL:  follow.FeedUrl,
+			HTMLURL: follow.FeedSiteUrl.String,
+		}
+		if !follow.FolderName.Valid {
+			doc.Body = append(doc.Body, feed)
+			continue
+		}
+
+		i, ok := folders[follow.FolderName.String]
+		if !ok {
+			i = len(doc.Body)
+			folders[follow.FolderName.String] = i
+			doc.Body = append(doc.Body, opmlOutline{
+				Text:  follow.FolderName.String,
+				Title: follow.FolderName.String,
+			})
+		}
+		doc.Body[i].Outlines = append(doc.Body[i].Outlines, feed)
+	}
+	return doc
+}
+
+// exportedPost is a post as written by export --posts
+type exportedPost struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url,omitempty"`
+	Feed        string     `json:"feed"`
+	Author      string     `json:"author,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Categories  []string   `json:"categories,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	Read        bool       `json:"read"`
+	Starred     bool       `json:"starred"`
+}
+
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ", ")
+}
+
+// mergePosts adds the posts of extra that posts does not have yet and sorts
+// them newest first
+func mergePosts(posts, extra []database.GetPostsForUserRow) []database.GetPostsForUserRow {
+	seen := make(map[uuid.UUID]bool, len(posts))
+	for _, post := range posts {
+		seen[post.ID] = true
+	}
+	for _, post := range extra {
+		if !seen[post.ID] {
+			seen[post.ID] = true
+			posts = append(posts, post)
+		}
+	}
+
+	postedAt := func(post database.GetPostsForUserRow) time.Time {
+		if post.PublishedAt.Valid {
+			return post.PublishedAt.Time
+		}
+		return post.CreatedAt
+	}
+	slices.SortStableFunc(posts, func(a, b database.GetPostsForUserRow) int {
+		return postedAt(b).Compare(postedAt(a))
+	})
+	return posts
+}
+
+// exportPosts prints the posts matching the browse filters as JSON
+func exportPosts(s *State, user database.User, args []string) error {
+	opts, err := parseBrowseArgs(args, math.MaxInt32)
+	if err != nil {
+		return err
+	}
+	posts, err := getPosts(s, user, opts)
+	if err != nil {
+		return err
+	}
+
+	// Starred and tagged posts are exported too, also those of feeds the
+	// user has since unfollowed or muted
+	if !opts.Starred && !opts.Tagged && opts.Tag == "" {
+		starred, tagged := opts, opts
+		starred.Starred = true
+		tagged.Tagged = true
+		for _, savedOpts := range []browseOptions{starred, tagged} {
+			saved, err := getPosts(s, user, savedOpts)
+			if err != nil {
+				return err
+			}
+			posts = mergePosts(posts, saved)
+		}
+		if len(posts) > int(opts.Limit) {
+			posts = posts[:opts.Limit]
+		}
+	}
+
+	exported := make([]exportedPost, 0, len(posts))
+	for _, post := range posts {
+		item := exportedPost{
+			ID:         post.ID.String(),
+			Title:      post.Title,
+			URL:        post.Url,
+			Feed:       post.FeedName,
+			Author:     post.Author.String,
+			Categories: splitList(post.Categories),
+			Tags:       splitList(post.Tags),
+			Read:       post.IsRead,
+			Starred:    post.IsStarred,
+		}
+		if post.PublishedAt.Valid {
+			item.PublishedAt = &post.PublishedAt.Time
+		}
+		exported = append(exported, item)
+	}
+
+	out, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to build JSON: %w", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// HandlerExport prints the user's subscriptions as OPML: export [--folder <name>],
+// or their posts with tags as JSON: export --posts [browse options]
+func HandlerExport(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) > 0 && cmd.Args[0] == "--posts" {
+		return exportPosts(s, user, cmd.Args[1:])
+	}
+
+	folderName := ""
+	switch {
+	case len(cmd.Args) == 0:
+	case len(cmd.Args) == 2 && cmd.Args[0] == "--folder":
+		folderName = cmd.Args[1]
+	default:
+		return fmt.Errorf("usage: export [--folder <name>] | export --posts [--tag <tag>] [--folder <name>]")
+	}
+
+	follows, err := s.DB.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get follows: %w", err)
+	}
+
+	if folderName != "" {
+		folder, err := getFolder(s, user, folderName)
+		if err != nil {
+			return err
+		}
+		var inFolder []database.GetFeedFollowsForUserRow
+		for _, follow := range follows {
+			if follow.FolderID.Valid && follow.FolderID.UUID == folder.ID {
+				inFolder = append(inFolder, follow)
+			}
+		}
+		follows = inFolder
+	}
+
+	out, err := xml.MarshalIndent(subscriptionsOPML(user, follows), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to build OPML: %w", err)
+	}
+	fmt.Printf("%s%s\n", xml.Header, out)
+	return nil
+}
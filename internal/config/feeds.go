@@ -0,0 +1,202 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/BabichevDima/aggregator/internal/database"
+)
+
+const editFeedUsage = "usage: editfeed <feed-url> [name=<name>] [url=<new-url>]"
+
+// feedToModify looks up a feed the user is allowed to edit or delete: one
+// they added, or any feed for an admin
+func feedToModify(s *State, user database.User, rawURL string) (database.Feed, error) {
+	feed, err := getFeedByURL(context.Background(), s.DB, rawURL)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return feed, fmt.Errorf("feed with URL '%s' does not exist", rawURL)
+		}
+		return feed, fmt.Errorf("database error: %w", err)
+	}
+	return feed, canModifyFeed(user, feed)
+}
+
+func canModifyFeed(user database.User, feed database.Feed) error {
+	if feed.UserID != user.ID && !user.IsAdmin {
+		return fmt.Errorf("only the user who added '%s' or an admin can change it", feed.Name)
+	}
+	return nil
+}
+
+// HandlerDeleteFeed deletes a feed with its posts: deletefeed <feed-url> [--transfer].
+// While others follow the feed it is refused; --transfer instead hands the
+// feed to its longest-standing follower and unfollows it.
+func HandlerDeleteFeed(s *State, cmd Command, user database.User) error {
+	transfer := false
+	switch {
+	case len(cmd.Args) == 1:
+	case len(cmd.Args) == 2 && cmd.Args[1] == "--transfer":
+		transfer = true
+	default:
+		return fmt.Errorf("usage: deletefeed <feed-url> [--transfer]")
+	}
+	ctx := context.Background()
+
+	feed, err := feedToModify(s, user, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	others, err := s.DB.GetOtherFollowers(ctx, database.GetOtherFollowersParams{
+		FeedID: feed.ID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get followers: %w", err)
+	}
+
+	if len(others) == 0 {
+		if err := s.DB.DeleteFeed(ctx, feed.ID); err != nil {
+			return fmt.Errorf("failed to delete feed: %w", err)
+		}
+		fmt.Printf("Deleted feed '%s' and its posts\n", feed.Name)
+		return nil
+	}
+
+	if !transfer {
+		return fmt.Errorf("%d other users follow '%s'; run deletefeed %s --transfer to hand it to %s instead",
+			len(others), feed.Name, cmd.Args[0], others[0].Name)
+	}
+
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.DB.WithTx(tx)
+
+	if err := qtx.SetFeedOwner(ctx, database.SetFeedOwnerParams{
+		ID:     feed.ID,
+		UserID: others[0].ID,
+	}); err != nil {
+		return fmt.Errorf("failed to transfer feed: %w", err)
+	}
+	if _, err := qtx.DeleteFeedFollowByURL(ctx, database.DeleteFeedFollowByURLParams{
+		UserID: user.ID,
+		UrlKey: feed.UrlKey,
+		Url:    feed.Url,
+	}); err != nil {
+		return fmt.Errorf("failed to unfollow: %w", err)
+	}
+	if err := qtx.MarkOrphanedFeeds(ctx); err != nil {
+		return fmt.Errorf("failed to update orphaned feeds: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	fmt.Printf("Feed '%s' now belongs to %s and you no longer follow it\n", feed.Name, others[0].Name)
+	return nil
+}
+
+// HandlerEditFeed renames a feed or points it at a new URL, which is fetched
+// first the way addfeed does
+func HandlerEditFeed(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 2 {
+		return errors.New(editFeedUsage)
+	}
+	ctx := context.Background()
+
+	feed, err := feedToModify(s, user, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	var name, rawURL string
+	for _, arg := range cmd.Args[1:] {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fmt.Errorf("invalid setting '%s', expected key=value", arg)
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "name":
+			if value == "" {
+				return fmt.Errorf("feed name cannot be empty")
+			}
+			name = value
+		case "url":
+			if value == "" {
+				return fmt.Errorf("feed URL cannot be empty")
+			}
+			rawURL = value
+		default:
+			return errors.New(editFeedUsage)
+		}
+	}
+
+	var newURL string
+	var rssFeed *RSSFeed
+	if rawURL != "" {
+		newURL, rssFeed, err = resolveFeedURL(ctx, s, rawURL)
+		if err != nil {
+			return fmt.Errorf("editfeed failed: %w", err)
+		}
+		newURL = normalizeURL(newURL)
+
+		existing, err := getFeedByURL(ctx, s.DB, newURL)
+		if err == nil && existing.ID != feed.ID {
+			return fmt.Errorf("feed '%s' already uses %s", existing.Name, existing.Url)
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("database error: %w", err)
+		}
+	}
+
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.DB.WithTx(tx)
+
+	if name != "" {
+		feed, err = qtx.RenameFeed(ctx, database.RenameFeedParams{
+			ID:   feed.ID,
+			Name: name,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to rename feed: %w", err)
+		}
+	}
+
+	if newURL != "" {
+		feed, err = qtx.UpdateFeedURL(ctx, database.UpdateFeedURLParams{
+			ID:     feed.ID,
+			Url:    newURL,
+			UrlKey: urlKeyParam(newURL),
+		})
+		if err != nil {
+			return fmt.Errorf("update feed url: %w", err)
+		}
+		metadata := feedMetadata(rssFeed)
+		metadata.ID = feed.ID
+		if err := qtx.UpdateFeedMetadata(ctx, metadata); err != nil {
+			return fmt.Errorf("update feed metadata: %w", err)
+		}
+		if err := qtx.ResetFeedFetchState(ctx, feed.ID); err != nil {
+			return fmt.Errorf("reset fetch state: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	fmt.Printf("Feed: %s (%s)\n", feed.Name, feed.Url)
+	return nil
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	defaultHTTPTimeout = 30 * time.Second
+	defaultMaxBodySize = 10 << 20 // 10 MiB
+	defaultUserAgent   = "gator"
+)
+
+// Fetcher performs every outgoing HTTP request of the aggregator
+// using the client settings from the config file.
+type Fetcher struct {
+	Client      *http.Client
+	UserAgent   string
+	MaxBodySize int64
+}
+
+// NewFetcher builds the HTTP client from the config: timeout, proxy and extra CA bundles.
+func NewFetcher(cfg *Config) (*Fetcher, error) {
+	timeout := defaultHTTPTimeout
+	if cfg.HTTPTimeout != "" {
+		parsed, err := time.ParseDuration(cfg.HTTPTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid http_timeout: %w", err)
+		}
+		timeout = parsed
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.HTTPProxy != "" {
+		proxyURL, err := url.Parse(cfg.HTTPProxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid http_proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if len(cfg.CABundles) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, path := range cfg.CABundles {
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+			}
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	fetcher := &Fetcher{
+		Client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+		},
+		UserAgent:   cfg.UserAgent,
+		MaxBodySize: cfg.MaxBodySize,
+	}
+	if fetcher.UserAgent == "" {
+		fetcher.UserAgent = defaultUserAgent
+	}
+	if fetcher.MaxBodySize <= 0 {
+		fetcher.MaxBodySize = defaultMaxBodySize
+	}
+
+	return fetcher, nil
+}
+
+// Get sends a GET request with our User-Agent.
+func (f *Fetcher) Get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	// set request headers
+	req.Header.Set("User-Agent", f.UserAgent)
+
+	res, err := f.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	return res, nil
+}
+
+// ReadBody reads the whole response body, refusing anything over MaxBodySize.
+func (f *Fetcher) ReadBody(res *http.Response) ([]byte, error) {
+	if res.ContentLength > f.MaxBodySize {
+		return nil, fmt.Errorf("response body is %d bytes, limit is %d", res.ContentLength, f.MaxBodySize)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, f.MaxBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	if int64(len(data)) > f.MaxBodySize {
+		return nil, fmt.Errorf("response body exceeds limit of %d bytes", f.MaxBodySize)
+	}
+	return data, nil
+}
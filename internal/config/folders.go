@@ -0,0 +1,143 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/BabichevDima/aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+const folderUsage = "usage: folder create <name> | folder add <feed-url> <folder> | folder remove <feed-url> | folder delete <name> | folder list"
+
+// getFolder looks up one of the user's folders by name
+func getFolder(s *State, user database.User, name string) (database.Folder, error) {
+	folder, err := s.DB.GetFolderByName(context.Background(), database.GetFolderByNameParams{
+		UserID: user.ID,
+		Name:   name,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return folder, fmt.Errorf("folder '%s' does not exist", name)
+		}
+		return folder, fmt.Errorf("database error: %w", err)
+	}
+	return folder, nil
+}
+
+// folderFilter turns an optional --folder value into a query parameter
+func folderFilter(s *State, user database.User, name string) (uuid.NullUUID, error) {
+	if name == "" {
+		return uuid.NullUUID{}, nil
+	}
+	folder, err := getFolder(s, user, name)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: folder.ID, Valid: true}, nil
+}
+
+func HandlerFolder(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) == 0 {
+		return errors.New(folderUsage)
+	}
+	ctx := context.Background()
+	args := cmd.Args[1:]
+
+	switch cmd.Args[0] {
+	case "create":
+		if err := validateArgs(args, 1, "folder create"); err != nil {
+			return err
+		}
+		name := strings.TrimSpace(args[0])
+		if name == "" {
+			return fmt.Errorf("folder name cannot be empty")
+		}
+		if _, err := s.DB.CreateFolder(ctx, database.CreateFolderParams{
+			ID:     uuid.New(),
+			UserID: user.ID,
+			Name:   name,
+		}); err != nil {
+			if strings.Contains(err.Error(), "duplicate key") {
+				return fmt.Errorf("folder '%s' already exists", name)
+			}
+			return fmt.Errorf("failed to create folder: %w", err)
+		}
+		fmt.Printf("Folder '%s' created\n", name)
+
+	case "add":
+		if err := validateArgs(args, 2, "folder add"); err != nil {
+			return err
+		}
+		folder, err := getFolder(s, user, args[1])
+		if err != nil {
+			return err
+		}
+		moved, err := s.DB.SetFollowFolder(ctx, database.SetFollowFolderParams{
+			FolderID: uuid.NullUUID{UUID: folder.ID, Valid: true},
+			UserID:   user.ID,
+			UrlKey:   urlKeyParam(args[0]),
+			Url:      strings.TrimSpace(args[0]),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to move feed: %w", err)
+		}
+		if moved == 0 {
+			return fmt.Errorf("you are not following '%s'", args[0])
+		}
+		fmt.Printf("Moved %s to '%s'\n", args[0], folder.Name)
+
+	case "remove":
+		if err := validateArgs(args, 1, "folder remove"); err != nil {
+			return err
+		}
+		moved, err := s.DB.SetFollowFolder(ctx, database.SetFollowFolderParams{
+			UserID: user.ID,
+			UrlKey: urlKeyParam(args[0]),
+			Url:    strings.TrimSpace(args[0]),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to move feed: %w", err)
+		}
+		if moved == 0 {
+			return fmt.Errorf("you are not following '%s'", args[0])
+		}
+		fmt.Printf("Moved %s out of its folder\n", args[0])
+
+	case "delete":
+		if err := validateArgs(args, 1, "folder delete"); err != nil {
+			return err
+		}
+		folder, err := getFolder(s, user, args[0])
+		if err != nil {
+			return err
+		}
+		// Feeds in the folder stay followed, at the top level
+		if err := s.DB.DeleteFolder(ctx, folder.ID); err != nil {
+			return fmt.Errorf("failed to delete folder: %w", err)
+		}
+		fmt.Printf("Folder '%s' deleted\n", folder.Name)
+
+	case "list":
+		if err := validateArgs(args, 0, "folder list"); err != nil {
+			return err
+		}
+		folders, err := s.DB.GetFoldersForUser(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get folders: %w", err)
+		}
+		if len(folders) == 0 {
+			fmt.Println("No folders yet")
+		}
+		for _, folder := range folders {
+			fmt.Printf("* %s (%d feeds)\n", folder.Name, folder.FeedCount)
+		}
+
+	default:
+		return errors.New(folderUsage)
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BabichevDima/aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Attachment kinds stored in attachments.kind
+const (
+	attachmentEnclosure = "enclosure"
+	attachmentMedia     = "media"
+	attachmentThumbnail = "thumbnail"
+	attachmentImage     = "image"
+)
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length string `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type mediaContent struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Medium   string `xml:"medium,attr"`
+	FileSize string `xml:"fileSize,attr"`
+	Duration string `xml:"duration,attr"`
+}
+
+type mediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+type mediaGroup struct {
+	Content    []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnails []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// postAttachment is a media file referenced by a feed item
+type postAttachment struct {
+	Kind            string
+	URL             string
+	MimeType        string
+	Length          int64
+	DurationSeconds int32
+	Episode         int32
+}
+
+// Attachments collects enclosures, Media RSS and iTunes tags of the item.
+// iTunes duration and episode describe the episode audio, so they go on the enclosure.
+func (item RSSItem) Attachments() []postAttachment {
+	var attachments []postAttachment
+	seen := make(map[string]bool)
+	add := func(attachment postAttachment) {
+		attachment.URL = strings.TrimSpace(attachment.URL)
+		if attachment.URL == "" || seen[attachment.URL] {
+			return
+		}
+		seen[attachment.URL] = true
+		attachments = append(attachments, attachment)
+	}
+
+	duration := parseMediaDuration(item.ITunesDuration)
+	episode, _ := strconv.ParseInt(strings.TrimSpace(item.ITunesEpisode), 10, 32)
+
+	for _, enclosure := range item.Enclosures {
+		length, _ := strconv.ParseInt(strings.TrimSpace(enclosure.Length), 10, 64)
+		add(postAttachment{
+			Kind:            attachmentEnclosure,
+			URL:             enclosure.URL,
+			MimeType:        enclosure.Type,
+			Length:          length,
+			DurationSeconds: duration,
+			Episode:         int32(episode),
+		})
+	}
+
+	contents := item.MediaContent
+	thumbnails := item.MediaThumbnails
+	for _, group := range item.MediaGroups {
+		contents = append(contents, group.Content...)
+		thumbnails = append(thumbnails, group.Thumbnails...)
+	}
+
+	for _, content := range contents {
+		length, _ := strconv.ParseInt(strings.TrimSpace(content.FileSize), 10, 64)
+		mimeType := content.Type
+		if mimeType == "" {
+			mimeType = content.Medium
+		}
+		add(postAttachment{
+			Kind:            attachmentMedia,
+			URL:             content.URL,
+			MimeType:        mimeType,
+			Length:          length,
+			DurationSeconds: parseMediaDuration(content.Duration),
+		})
+	}
+	for _, thumbnail := range thumbnails {
+		add(postAttachment{Kind: attachmentThumbnail, URL: thumbnail.URL})
+	}
+	add(postAttachment{Kind: attachmentImage, URL: item.ITunesImage.Href})
+
+	return attachments
+}
+
+// parseMediaDuration understands "3600", "59:59" and "1:02:03"
+func parseMediaDuration(value string) int32 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	var total float64
+	for _, part := range strings.Split(value, ":") {
+		number, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0
+		}
+		total = total*60 + number
+	}
+	return int32(total)
+}
+
+// saveAttachments replaces the attachments of a post with the ones from the feed
+func saveAttachments(ctx context.Context, qtx *database.Queries, postID uuid.UUID, item RSSItem) error {
+	if err := qtx.DeletePostAttachments(ctx, postID); err != nil {
+		return fmt.Errorf("delete attachments: %w", err)
+	}
+
+	for _, attachment := range item.Attachments() {
+		if err := qtx.CreateAttachment(ctx, database.CreateAttachmentParams{
+			ID:              uuid.New(),
+			CreatedAt:       time.Now(),
+			PostID:          postID,
+			Kind:            attachment.Kind,
+			Url:             attachment.URL,
+			MimeType:        nullString(attachment.MimeType),
+			Length:          sql.NullInt64{Int64: attachment.Length, Valid: attachment.Length > 0},
+			DurationSeconds: sql.NullInt32{Int32: attachment.DurationSeconds, Valid: attachment.DurationSeconds > 0},
+			Episode:         sql.NullInt32{Int32: attachment.Episode, Valid: attachment.Episode > 0},
+		}); err != nil {
+			return fmt.Errorf("save attachment: %w", err)
+		}
+	}
+	return nil
+}
+
+// formatAttachment renders one line of the attachment list in browse
+func formatAttachment(attachment database.GetAttachmentsForPostRow) string {
+	parts := []string{attachment.Kind}
+	if attachment.MimeType.Valid {
+		parts = append(parts, attachment.MimeType.String)
+	}
+	if attachment.Length.Valid {
+		parts = append(parts, fmt.Sprintf("%.1f MB", float64(attachment.Length.Int64)/(1<<20)))
+	}
+	if attachment.DurationSeconds.Valid {
+		parts = append(parts, (time.Duration(attachment.DurationSeconds.Int32) * time.Second).String())
+	}
+	if attachment.Episode.Valid {
+		parts = append(parts, fmt.Sprintf("episode %d", attachment.Episode.Int32))
+	}
+	return fmt.Sprintf("%s %s", strings.Join(parts, ", "), attachment.Url)
+}
+
+func HandlerDownload(s *State, cmd Command) error {
+	if err := validateArgs(cmd.Args, 1, "download"); err != nil {
+		return err
+	}
+
+	postID, err := parsePostID(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	attachments, err := s.DB.GetAttachmentsForPost(context.Background(), postID)
+	if err != nil {
+		return fmt.Errorf("failed to get attachments: %w", err)
+	}
+
+	dir, err := downloadDir(s.Config)
+	if err != nil {
+		return err
+	}
+
+	downloaded := 0
+	for _, attachment := range attachments {
+		// Thumbnails and cover art are not what people download
+		if attachment.Kind != attachmentEnclosure && attachment.Kind != attachmentMedia {
+			continue
+		}
+
+		feedDir := safeFileName(attachment.FeedName)
+		if feedDir == "" {
+			feedDir = "unnamed-feed"
+		}
+		dest := filepath.Join(dir, feedDir, attachmentFileName(attachment))
+		if err := downloadFile(context.Background(), s.Fetcher, attachment.Url, dest); err != nil {
+			return fmt.Errorf("failed to download %s: %w", attachment.Url, err)
+		}
+		downloaded++
+	}
+
+	if downloaded == 0 {
+		fmt.Println("The post has no media to download")
+	}
+	return nil
+}
+
+func downloadDir(cfg *Config) (string, error) {
+	if cfg.DownloadDir != "" {
+		return cfg.DownloadDir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, "gator-downloads"), nil
+}
+
+// attachmentFileName starts with the post ID and a short hash of the URL, as
+// episodes often share a file name: .../ep1/audio.mp3 and .../ep2/audio.mp3.
+// Attachment rows are recreated on every post update, so their IDs would give
+// the same file a new name and break resuming.
+func attachmentFileName(attachment database.GetAttachmentsForPostRow) string {
+	sum := sha256.Sum256([]byte(attachment.Url))
+	prefix := attachment.PostID.String() + "-" + hex.EncodeToString(sum[:])[:8]
+
+	name := ""
+	if parsed, err := url.Parse(attachment.Url); err == nil {
+		name = safeFileName(path.Base(parsed.Path))
+	}
+	if name == "" || name == "_" {
+		return prefix
+	}
+	return prefix + "-" + name
+}
+
+// safeFileName keeps a name usable as a single path element. Names that would
+// refer to a directory instead, "", "." and "..", come back empty.
+func safeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', 0:
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if strings.Trim(name, ".") == "" {
+		return ""
+	}
+	return name
+}
+
+// downloadFile saves rawURL to dest. Data goes to dest.part first, so an
+// interrupted download resumes from where it stopped using a Range request.
+func downloadFile(ctx context.Context, fetcher *Fetcher, rawURL, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		fmt.Printf("Already downloaded: %s\n", dest)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("create download directory: %w", err)
+	}
+
+	partial := dest + ".part"
+	var offset int64
+	if info, err := os.Stat(partial); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", fetcher.UserAgent)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	// Media files are large - the feed timeout would cut them off
+	client := *fetcher.Client
+	client.Timeout = 0
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("making request: %w", err)
+	}
+	defer res.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+		fmt.Printf("Resuming %s at %d bytes\n", rawURL, offset)
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// the server ignored the Range header, start over
+		fmt.Printf("Downloading %s\n", rawURL)
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the partial file is already complete, unless there is none
+		if _, err := os.Stat(partial); err != nil {
+			return fmt.Errorf("unexpected status code: %d", res.StatusCode)
+		}
+		if err := os.Rename(partial, dest); err != nil {
+			return fmt.Errorf("finish download: %w", err)
+		}
+		fmt.Printf("Saved to %s\n", dest)
+		return nil
+	default:
+		return fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+
+	file, err := os.OpenFile(partial, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", partial, err)
+	}
+
+	written, err := io.Copy(file, res.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+		return fmt.Errorf("download interrupted after %d bytes, run download again to resume: %w", written, err)
+	}
+
+	if err := os.Rename(partial, dest); err != nil {
+		return fmt.Errorf("finish download: %w", err)
+	}
+
+	fmt.Printf("Saved to %s\n", dest)
+	return nil
+}
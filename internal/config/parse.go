@@ -0,0 +1,341 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// errNotAFeed is returned by parseFeed when the document is an HTML page rather than a feed
+var errNotAFeed = errors.New("document is an HTML page, not a feed")
+
+// parseFeed turns an RSS 2.0, RSS 1.0 (RDF), Atom or JSON Feed document into an RSSFeed.
+func parseFeed(data []byte, contentType string) (*RSSFeed, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if mediaType == "application/feed+json" || mediaType == "application/json" || bytes.HasPrefix(trimmed, []byte("{")) {
+		return parseJSONFeed(data)
+	}
+
+	// Many servers send feeds as text/html, so the content type alone does not
+	// make a document a page; the root element decides
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := decodeXML(data, contentType, &root); err != nil {
+		if mediaType == "text/html" || mediaType == "application/xhtml+xml" ||
+			strings.HasPrefix(http.DetectContentType(data), "text/html") {
+			return nil, errNotAFeed
+		}
+		return nil, fmt.Errorf("parsing XML: %w", err)
+	}
+
+	switch strings.ToLower(root.XMLName.Local) {
+	case "rss":
+		var rssFeed RSSFeed
+		if err := decodeXML(data, contentType, &rssFeed); err != nil {
+			return nil, fmt.Errorf("parsing RSS: %w", err)
+		}
+		for _, link := range rssFeed.Channel.Links {
+			if link.XMLName.Space == "" {
+				rssFeed.Channel.Link = strings.TrimSpace(link.Value)
+				break
+			}
+		}
+		return &rssFeed, nil
+	case "rdf":
+		return parseRDFFeed(data, contentType)
+	case "feed":
+		return parseAtomFeed(data, contentType)
+	case "html":
+		return nil, errNotAFeed
+	}
+	return nil, fmt.Errorf("unsupported feed format <%s>", root.XMLName.Local)
+}
+
+type rdfFeed struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+	} `xml:"channel"`
+	Item []struct {
+		About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+		Title       string   `xml:"title"`
+		Link        string   `xml:"link"`
+		Description string   `xml:"description"`
+		Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+		Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+		Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	} `xml:"item"`
+}
+
+func parseRDFFeed(data []byte, contentType string) (*RSSFeed, error) {
+	var rdf rdfFeed
+	if err := decodeXML(data, contentType, &rdf); err != nil {
+		return nil, fmt.Errorf("parsing RDF: %w", err)
+	}
+
+	var rssFeed RSSFeed
+	rssFeed.Channel.Title = rdf.Channel.Title
+	rssFeed.Channel.Link = rdf.Channel.Link
+	rssFeed.Channel.Description = rdf.Channel.Description
+	for _, item := range rdf.Item {
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, RSSItem{
+			GUID:        item.About,
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			PubDate:     item.Date,
+			Content:     item.Content,
+			Creator:     item.Creator,
+			Categories:  item.Subjects,
+		})
+	}
+	return &rssFeed, nil
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// atomText keeps the raw markup of text constructs, which may be type="xhtml"
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",innerxml"`
+}
+
+type atomFeed struct {
+	Base     string      `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Lang     string      `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Title    atomText    `xml:"title"`
+	Subtitle atomText    `xml:"subtitle"`
+	Links    []atomLink  `xml:"link"`
+	Icon     string      `xml:"icon"`
+	Logo     string      `xml:"logo"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Base      string     `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	ID        string     `xml:"id"`
+	Title     atomText   `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Summary   atomText   `xml:"summary"`
+	Content   atomText   `xml:"content"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Authors   []struct {
+		Name  string `xml:"name"`
+		Email string `xml:"email"`
+	} `xml:"author"`
+	Categories []struct {
+		Term  string `xml:"term,attr"`
+		Label string `xml:"label,attr"`
+	} `xml:"category"`
+}
+
+func parseAtomFeed(data []byte, contentType string) (*RSSFeed, error) {
+	var atom atomFeed
+	if err := decodeXML(data, contentType, &atom); err != nil {
+		return nil, fmt.Errorf("parsing Atom: %w", err)
+	}
+
+	var rssFeed RSSFeed
+	rssFeed.Base = atom.Base
+	rssFeed.Channel.Title = atom.Title.text()
+	rssFeed.Channel.Link = alternateLink(atom.Links)
+	rssFeed.Channel.Description = atom.Subtitle.text()
+	rssFeed.Channel.Language = atom.Lang
+	rssFeed.Channel.Image.URL = atom.Logo
+	if rssFeed.Channel.Image.URL == "" {
+		rssFeed.Channel.Image.URL = atom.Icon
+	}
+
+	for _, entry := range atom.Entries {
+		description := entry.Summary.text()
+		if description == "" {
+			description = entry.Content.text()
+		}
+		published := entry.Published
+		if published == "" {
+			published = entry.Updated
+		}
+
+		item := RSSItem{
+			Base:        entry.Base,
+			GUID:        entry.ID,
+			Title:       entry.Title.text(),
+			Link:        alternateLink(entry.Links),
+			Description: description,
+			PubDate:     published,
+			Content:     entry.Content.text(),
+		}
+		for _, author := range entry.Authors {
+			if author.Name != "" {
+				item.Creator = author.Name
+				break
+			}
+		}
+		for _, category := range entry.Categories {
+			if category.Label != "" {
+				item.Categories = append(item.Categories, category.Label)
+			} else if category.Term != "" {
+				item.Categories = append(item.Categories, category.Term)
+			}
+		}
+		for _, link := range entry.Links {
+			switch {
+			case link.Rel == "replies" && link.Type != "application/atom+xml":
+				item.Comments = link.Href
+			case link.Rel == "enclosure":
+				item.Enclosures = append(item.Enclosures, rssEnclosure{
+					URL:    link.Href,
+					Type:   link.Type,
+					Length: link.Length,
+				})
+			}
+		}
+
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, item)
+	}
+	return &rssFeed, nil
+}
+
+// text returns the content in the same shape an RSS element has after
+// fetchFeed unescapes it: html and text stay escaped, xhtml stays markup.
+func (t atomText) text() string {
+	body := strings.TrimSpace(t.Body)
+	// CDATA sections come through innerxml verbatim
+	if strings.HasPrefix(body, "<![CDATA[") && strings.HasSuffix(body, "]]>") {
+		return strings.TrimSuffix(strings.TrimPrefix(body, "<![CDATA["), "]]>")
+	}
+	return body
+}
+
+func alternateLink(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+type jsonFeed struct {
+	Title       string `json:"title"`
+	HomePageURL string `json:"home_page_url"`
+	Description string `json:"description"`
+	Language    string `json:"language"`
+	Icon        string `json:"icon"`
+	Favicon     string `json:"favicon"`
+	Items       []struct {
+		ID            json.RawMessage `json:"id"`
+		URL           string          `json:"url"`
+		Title         string          `json:"title"`
+		ContentHTML   string          `json:"content_html"`
+		ContentText   string          `json:"content_text"`
+		Summary       string          `json:"summary"`
+		DatePublished string          `json:"date_published"`
+		Tags          []string        `json:"tags"`
+		Author        *jsonAuthor     `json:"author"`
+		Authors       []jsonAuthor    `json:"authors"`
+		Image         string          `json:"image"`
+		Attachments   []struct {
+			URL      string  `json:"url"`
+			MimeType string  `json:"mime_type"`
+			Size     int64   `json:"size_in_bytes"`
+			Duration float64 `json:"duration_in_seconds"`
+		} `json:"attachments"`
+	} `json:"items"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+func parseJSONFeed(data []byte) (*RSSFeed, error) {
+	var feed jsonFeed
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return nil, fmt.Errorf("parsing JSON feed: %w", err)
+	}
+
+	var rssFeed RSSFeed
+	rssFeed.Channel.Title = feed.Title
+	rssFeed.Channel.Link = feed.HomePageURL
+	rssFeed.Channel.Description = feed.Description
+	rssFeed.Channel.Language = feed.Language
+	rssFeed.Channel.Image.URL = feed.Icon
+	if rssFeed.Channel.Image.URL == "" {
+		rssFeed.Channel.Image.URL = feed.Favicon
+	}
+
+	for _, item := range feed.Items {
+		content := item.ContentHTML
+		if content == "" {
+			content = item.ContentText
+		}
+		description := item.Summary
+		if description == "" {
+			description = content
+		}
+
+		author := ""
+		if len(item.Authors) > 0 {
+			author = item.Authors[0].Name
+		} else if item.Author != nil {
+			author = item.Author.Name
+		}
+
+		rssItem := RSSItem{
+			GUID:        jsonFeedID(item.ID),
+			Title:       item.Title,
+			Link:        item.URL,
+			Description: description,
+			PubDate:     item.DatePublished,
+			Content:     content,
+			Creator:     author,
+			Categories:  item.Tags,
+		}
+		for _, attachment := range item.Attachments {
+			rssItem.MediaContent = append(rssItem.MediaContent, mediaContent{
+				URL:      attachment.URL,
+				Type:     attachment.MimeType,
+				FileSize: strconv.FormatInt(attachment.Size, 10),
+				Duration: strconv.FormatFloat(attachment.Duration, 'f', 0, 64),
+			})
+		}
+		if item.Image != "" {
+			rssItem.MediaThumbnails = append(rssItem.MediaThumbnails, mediaThumbnail{URL: item.Image})
+		}
+
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, rssItem)
+	}
+	return &rssFeed, nil
+}
+
+// jsonFeedID accepts ids published as strings (per spec) or as bare numbers
+func jsonFeedID(raw json.RawMessage) string {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+	if id := strings.TrimSpace(string(raw)); id != "null" {
+		return id
+	}
+	return ""
+}
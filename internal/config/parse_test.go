@@ -0,0 +1,285 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+const testRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel>
+	<title>Example Blog</title>
+	<atom:link href="https://example.com/feed.xml" rel="self" type="application/rss+xml"/>
+	<link>https://example.com/</link>
+	<description>Posts</description>
+	<item>
+		<title>First post</title>
+		<link>https://example.com/first</link>
+		<guid>post-1</guid>
+		<pubDate>Tue, 05 Mar 2024 14:30:00 GMT</pubDate>
+		<dc:creator>Ann</dc:creator>
+		<category>go</category>
+		<description>&lt;p&gt;Hello&lt;/p&gt;</description>
+	</item>
+</channel>
+</rss>`
+
+const testRDF = `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+	<channel rdf:about="https://example.com/">
+		<title>Example Blog</title>
+		<link>https://example.com/</link>
+		<description>Posts</description>
+	</channel>
+	<item rdf:about="https://example.com/first">
+		<title>First post</title>
+		<link>https://example.com/first</link>
+		<dc:date>2024-03-05T14:30:00Z</dc:date>
+		<dc:creator>Ann</dc:creator>
+		<dc:subject>go</dc:subject>
+	</item>
+</rdf:RDF>`
+
+const testAtom = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="en">
+	<title>Example Blog</title>
+	<subtitle>Posts</subtitle>
+	<link rel="self" href="https://example.com/atom.xml"/>
+	<link href="https://example.com/"/>
+	<entry>
+		<id>tag:example.com,2024:1</id>
+		<title type="html">First post</title>
+		<link rel="alternate" href="https://example.com/first"/>
+		<link rel="enclosure" href="https://example.com/a.mp3" type="audio/mpeg" length="100"/>
+		<updated>2024-03-06T10:00:00Z</updated>
+		<published>2024-03-05T14:30:00Z</published>
+		<author><name>Ann</name></author>
+		<category term="go" label="Go"/>
+		<summary><![CDATA[<p>Hello</p>]]></summary>
+	</entry>
+</feed>`
+
+const testJSONFeed = `{
+	"version": "https://jsonfeed.org/version/1.1",
+	"title": "Example Blog",
+	"home_page_url": "https://example.com/",
+	"items": [
+		{
+			"id": 1,
+			"url": "https://example.com/first",
+			"title": "First post",
+			"content_html": "<p>Hello</p>",
+			"date_published": "2024-03-05T14:30:00Z",
+			"authors": [{"name": "Ann"}],
+			"tags": ["go"]
+		}
+	]
+}`
+
+const testHTMLPage = `<!DOCTYPE html>
+<html>
+<head><title>Example Blog</title>
+<link rel="alternate" type="application/rss+xml" href="/feed.xml">
+</head>
+<body><p>Welcome</p></body>
+</html>`
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		contentType string
+		title       string
+		link        string
+		item        RSSItem
+	}{
+		{
+			name:        "RSS",
+			data:        testRSS,
+			contentType: "application/rss+xml",
+			title:       "Example Blog",
+			link:        "https://example.com/",
+			item: RSSItem{
+				GUID:        "post-1",
+				Title:       "First post",
+				Link:        "https://example.com/first",
+				PubDate:     "Tue, 05 Mar 2024 14:30:00 GMT",
+				Creator:     "Ann",
+				Description: "<p>Hello</p>",
+			},
+		},
+		{
+			name:        "RSS served as text/html",
+			data:        testRSS,
+			contentType: "text/html; charset=utf-8",
+			title:       "Example Blog",
+			link:        "https://example.com/",
+			item: RSSItem{
+				GUID:        "post-1",
+				Title:       "First post",
+				Link:        "https://example.com/first",
+				PubDate:     "Tue, 05 Mar 2024 14:30:00 GMT",
+				Creator:     "Ann",
+				Description: "<p>Hello</p>",
+			},
+		},
+		{
+			name:        "RSS without a content type",
+			data:        testRSS,
+			contentType: "",
+			title:       "Example Blog",
+			link:        "https://example.com/",
+			item: RSSItem{
+				GUID:        "post-1",
+				Title:       "First post",
+				Link:        "https://example.com/first",
+				PubDate:     "Tue, 05 Mar 2024 14:30:00 GMT",
+				Creator:     "Ann",
+				Description: "<p>Hello</p>",
+			},
+		},
+		{
+			name:        "RDF",
+			data:        testRDF,
+			contentType: "application/rdf+xml",
+			title:       "Example Blog",
+			link:        "https://example.com/",
+			item: RSSItem{
+				GUID:    "https://example.com/first",
+				Title:   "First post",
+				Link:    "https://example.com/first",
+				PubDate: "2024-03-05T14:30:00Z",
+				Creator: "Ann",
+			},
+		},
+		{
+			name:        "Atom",
+			data:        testAtom,
+			contentType: "application/atom+xml",
+			title:       "Example Blog",
+			link:        "https://example.com/",
+			item: RSSItem{
+				GUID:        "tag:example.com,2024:1",
+				Title:       "First post",
+				Link:        "https://example.com/first",
+				PubDate:     "2024-03-05T14:30:00Z",
+				Creator:     "Ann",
+				Description: "<p>Hello</p>",
+			},
+		},
+		{
+			name:        "JSON Feed",
+			data:        testJSONFeed,
+			contentType: "application/feed+json",
+			title:       "Example Blog",
+			link:        "https://example.com/",
+			item: RSSItem{
+				GUID:        "1",
+				Title:       "First post",
+				Link:        "https://example.com/first",
+				PubDate:     "2024-03-05T14:30:00Z",
+				Creator:     "Ann",
+				Description: "<p>Hello</p>",
+			},
+		},
+		{
+			name:        "JSON Feed served as text/plain",
+			data:        testJSONFeed,
+			contentType: "text/plain",
+			title:       "Example Blog",
+			link:        "https://example.com/",
+			item: RSSItem{
+				GUID:        "1",
+				Title:       "First post",
+				Link:        "https://example.com/first",
+				PubDate:     "2024-03-05T14:30:00Z",
+				Creator:     "Ann",
+				Description: "<p>Hello</p>",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed([]byte(tt.data), tt.contentType)
+			if err != nil {
+				t.Fatalf("parseFeed returned error: %v", err)
+			}
+			if feed.Channel.Title != tt.title {
+				t.Errorf("title = %q, want %q", feed.Channel.Title, tt.title)
+			}
+			if feed.Channel.Link != tt.link {
+				t.Errorf("link = %q, want %q", feed.Channel.Link, tt.link)
+			}
+			if len(feed.Channel.Item) != 1 {
+				t.Fatalf("got %d items, want 1", len(feed.Channel.Item))
+			}
+
+			item := feed.Channel.Item[0]
+			if item.GUID != tt.item.GUID {
+				t.Errorf("guid = %q, want %q", item.GUID, tt.item.GUID)
+			}
+			if item.Title != tt.item.Title {
+				t.Errorf("item title = %q, want %q", item.Title, tt.item.Title)
+			}
+			if item.Link != tt.item.Link {
+				t.Errorf("item link = %q, want %q", item.Link, tt.item.Link)
+			}
+			if item.PubDate != tt.item.PubDate {
+				t.Errorf("pubDate = %q, want %q", item.PubDate, tt.item.PubDate)
+			}
+			if item.Creator != tt.item.Creator {
+				t.Errorf("creator = %q, want %q", item.Creator, tt.item.Creator)
+			}
+			if item.Description != tt.item.Description {
+				t.Errorf("description = %q, want %q", item.Description, tt.item.Description)
+			}
+		})
+	}
+}
+
+func TestParseFeedAtom(t *testing.T) {
+	feed, err := parseFeed([]byte(testAtom), "application/atom+xml")
+	if err != nil {
+		t.Fatalf("parseFeed returned error: %v", err)
+	}
+	if feed.Channel.Description != "Posts" || feed.Channel.Language != "en" {
+		t.Errorf("description %q, language %q", feed.Channel.Description, feed.Channel.Language)
+	}
+
+	item := feed.Channel.Item[0]
+	if len(item.Categories) != 1 || item.Categories[0] != "Go" {
+		t.Errorf("categories = %q, want the label", item.Categories)
+	}
+	if len(item.Enclosures) != 1 || item.Enclosures[0].URL != "https://example.com/a.mp3" {
+		t.Errorf("enclosures = %+v, want the enclosure link", item.Enclosures)
+	}
+}
+
+func TestParseFeedErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		contentType string
+		notAFeed    bool
+	}{
+		{"HTML page", testHTMLPage, "text/html; charset=utf-8", true},
+		{"HTML page without a content type", testHTMLPage, "", true},
+		{"XHTML page", `<?xml version="1.0"?><html xmlns="http://www.w3.org/1999/xhtml"><body/></html>`, "application/xml", true},
+		{"unknown XML", `<?xml version="1.0"?><sitemap><url/></sitemap>`, "application/xml", false},
+		{"broken XML", `<rss><channel><title>x</channel>`, "application/rss+xml", false},
+		{"broken JSON", `{"title": `, "application/json", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed([]byte(tt.data), tt.contentType)
+			if err == nil {
+				t.Fatalf("parseFeed = %+v, want an error", feed)
+			}
+			if got := errors.Is(err, errNotAFeed); got != tt.notAFeed {
+				t.Errorf("parseFeed error = %v, errNotAFeed %v, want %v", err, got, tt.notAFeed)
+			}
+		})
+	}
+}
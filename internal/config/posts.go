@@ -0,0 +1,375 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BabichevDima/aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// contentHashVersion prefixes hashes so that changing what postContentHash covers
+// refreshes stored posts instead of reporting every one of them as edited
+const contentHashVersion = "v4:"
+
+// postContentHash fingerprints the parts of an item that make up an edit upstream
+func postContentHash(item RSSItem) string {
+	hash := sha256.New()
+	hash.Write([]byte(strings.Join([]string{
+		item.Title,
+		strings.TrimSpace(item.Link),
+		item.Description,
+		item.PubDate,
+		item.Content,
+		item.PostAuthor(),
+		strings.Join(item.Categories, "\x01"),
+		strings.TrimSpace(item.Comments),
+	}, "\x00")))
+	for _, attachment := range item.Attachments() {
+		hash.Write([]byte(attachment.URL + "\x00"))
+	}
+	return contentHashVersion + hex.EncodeToString(hash.Sum(nil))
+}
+
+// samePostItem reports whether a post with guid and the link key key is the
+// item already stored as keeperGUID under the same key. Feeds that point every
+// item at one page (podcasts, the site root) share a key across items, so the
+// key alone is not enough: the GUIDs must match once canonicalized, or the
+// GUID must be the link itself, as postGUID makes it for items without one.
+func samePostItem(keeperGUID, guid, key string) bool {
+	if urlKey(keeperGUID) == urlKey(guid) {
+		return true
+	}
+	return urlKey(guid) == key
+}
+
+// findSamePost looks for a stored copy of a new item under its link key
+func findSamePost(ctx context.Context, db *database.Queries, feedID uuid.UUID, guid, key string) (database.Post, bool, error) {
+	if key == "" {
+		return database.Post{}, false, nil
+	}
+	posts, err := db.GetPostsByURLKey(ctx, database.GetPostsByURLKeyParams{
+		FeedID: feedID,
+		UrlKey: sql.NullString{String: key, Valid: true},
+	})
+	if err != nil {
+		return database.Post{}, false, fmt.Errorf("database error: %w", err)
+	}
+	for _, post := range posts {
+		if samePostItem(post.Guid, guid, key) {
+			return post, true, nil
+		}
+	}
+	return database.Post{}, false, nil
+}
+
+// savePost stores a feed item. A new item is inserted; an item whose content
+// changed upstream updates the post and keeps the old version in post_revisions.
+func savePost(s *State, feed database.Feed, item RSSItem) error {
+	ctx := context.Background()
+	guid := postGUID(item)
+	hash := postContentHash(item)
+
+	existing, err := s.DB.GetPostByGUID(ctx, database.GetPostByGUIDParams{
+		FeedID: feed.ID,
+		Guid:   guid,
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("database error: %w", err)
+	}
+	isNew := err != nil
+
+	if isNew {
+		// Пост уже удалён политикой хранения - не возвращаем его
+		pruned, err := s.DB.IsPostPruned(ctx, database.IsPostPrunedParams{
+			FeedID: feed.ID,
+			Guid:   guid,
+		})
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+		if pruned {
+			return nil
+		}
+
+		// Тот же пост мог быть сохранён под другим написанием ссылки
+		match, found, err := findSamePost(ctx, s.DB, feed.ID, guid, urlKey(item.Link))
+		if err != nil {
+			return err
+		}
+		if found {
+			existing, isNew = match, false
+		}
+	}
+	if !isNew && existing.ContentHash == hash {
+		return nil
+	}
+	// A hash from an older postContentHash is not an edit, the row just needs refreshing
+	isEdit := !isNew && strings.HasPrefix(existing.ContentHash, contentHashVersion)
+
+	// Без даты пост остаётся на месте своего первого появления
+	firstSeen := time.Now()
+	if !isNew {
+		firstSeen = existing.CreatedAt
+		if existing.PublishedAt.Valid {
+			firstSeen = existing.PublishedAt.Time
+		}
+	}
+	publishedAt := postPublishedAt(item.PubDate, firstSeen)
+
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.DB.WithTx(tx)
+
+	postID := existing.ID
+	if isNew {
+		post, err := qtx.CreatePost(ctx, database.CreatePostParams{
+			ID:          uuid.New(),
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+			Title:       item.Title,
+			Url:         normalizeURL(item.Link),
+			Description: nullString(sanitizeHTML(item.Description)),
+			PublishedAt: sql.NullTime{Time: publishedAt, Valid: !publishedAt.IsZero()},
+			FeedID:      feed.ID,
+			Guid:        guid,
+			ContentHash: hash,
+			Content:     nullString(sanitizeHTML(item.Content)),
+			Author:      nullString(item.PostAuthor()),
+			CommentsUrl: nullString(item.Comments),
+			UrlKey:      urlKeyParam(item.Link),
+		})
+		if err != nil {
+			if strings.Contains(err.Error(), "duplicate key") {
+				// Пост уже сохранён параллельным agg - пропускаем
+				return nil
+			}
+			return err
+		}
+		postID = post.ID
+	} else {
+		updatedAt := existing.UpdatedAt
+		if isEdit {
+			if err := qtx.CreatePostRevision(ctx, database.CreatePostRevisionParams{
+				ID:     uuid.New(),
+				PostID: existing.ID,
+			}); err != nil {
+				return fmt.Errorf("save revision: %w", err)
+			}
+			updatedAt = time.Now()
+		}
+
+		if err := qtx.UpdatePostContent(ctx, database.UpdatePostContentParams{
+			ID:          existing.ID,
+			Title:       item.Title,
+			Url:         normalizeURL(item.Link),
+			Description: nullString(sanitizeHTML(item.Description)),
+			PublishedAt: sql.NullTime{Time: publishedAt, Valid: !publishedAt.IsZero()},
+			ContentHash: hash,
+			Content:     nullString(sanitizeHTML(item.Content)),
+			Author:      nullString(item.PostAuthor()),
+			CommentsUrl: nullString(item.Comments),
+			UpdatedAt:   updatedAt,
+			UrlKey:      urlKeyParam(item.Link),
+		}); err != nil {
+			return fmt.Errorf("update post: %w", err)
+		}
+
+		if isEdit && s.Config.MarkUpdatedUnread {
+			if err := qtx.MarkPostUnreadForAll(ctx, existing.ID); err != nil {
+				return fmt.Errorf("mark post unread: %w", err)
+			}
+		}
+	}
+
+	if err := savePostCategories(ctx, qtx, postID, item.Categories); err != nil {
+		return err
+	}
+	if err := saveAttachments(ctx, qtx, postID, item); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if isNew {
+		// Подписчики, включившие уведомления, узнают о новом посте
+		if err := applyIngestRules(s, feed, postID, item); err != nil {
+			fmt.Printf("Error applying rules: %v\n", err)
+		}
+	}
+
+	switch {
+	case isNew:
+		fmt.Printf("Saved post: %s\n", item.Title)
+	case isEdit:
+		fmt.Printf("Updated post: %s\n", item.Title)
+	}
+
+	// Фид отдаёт только анонс - забираем статью целиком со страницы поста
+	if feed.ExtractFullText && (isNew || isEdit) && strings.TrimSpace(item.Link) != "" {
+		if _, err := saveArticle(s, postID, strings.TrimSpace(item.Link)); err != nil {
+			fmt.Printf("Error extracting article '%s': %v\n", item.Title, err)
+		}
+	}
+
+	// Снимок для офлайн-чтения, пока источник ещё доступен
+	if isNew && s.Config.ArchiveMode == archiveModeAll {
+		post, err := s.DB.GetPostByID(ctx, postID)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+		if _, err := archivePost(s, post, uuid.NullUUID{}); err != nil {
+			fmt.Printf("Error archiving '%s': %v\n", item.Title, err)
+		}
+	}
+	return nil
+}
+
+// savePostCategories replaces the categories of a post with the ones from the feed
+func savePostCategories(ctx context.Context, qtx *database.Queries, postID uuid.UUID, categories []string) error {
+	if err := qtx.DeletePostCategories(ctx, postID); err != nil {
+		return fmt.Errorf("delete categories: %w", err)
+	}
+	for _, category := range categories {
+		category = strings.TrimSpace(category)
+		if category == "" {
+			continue
+		}
+		if err := qtx.AddPostCategory(ctx, database.AddPostCategoryParams{
+			PostID: postID,
+			Name:   category,
+		}); err != nil {
+			return fmt.Errorf("save category: %w", err)
+		}
+	}
+	return nil
+}
+
+func parsePostID(arg string) (uuid.UUID, error) {
+	id, err := uuid.Parse(arg)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid post id '%s'", arg)
+	}
+	return id, nil
+}
+
+func HandlerRead(s *State, cmd Command, user database.User) error {
+	if err := validateArgs(cmd.Args, 1, "read"); err != nil {
+		return err
+	}
+
+	postID, err := parsePostID(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := s.DB.MarkPostRead(context.Background(), database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: postID,
+	}); err != nil {
+		return fmt.Errorf("failed to mark post as read: %w", err)
+	}
+
+	fmt.Printf("Post %s marked as read\n", postID)
+	return nil
+}
+
+func HandlerUnread(s *State, cmd Command, user database.User) error {
+	if err := validateArgs(cmd.Args, 1, "unread"); err != nil {
+		return err
+	}
+
+	postID, err := parsePostID(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := s.DB.MarkPostUnread(context.Background(), database.MarkPostUnreadParams{
+		UserID: user.ID,
+		PostID: postID,
+	}); err != nil {
+		return fmt.Errorf("failed to mark post as unread: %w", err)
+	}
+
+	fmt.Printf("Post %s marked as unread\n", postID)
+	return nil
+}
+
+// HandlerMarkAllRead marks every post of the user's feeds as read: markallread [--folder <name>]
+func HandlerMarkAllRead(s *State, cmd Command, user database.User) error {
+	folderName := ""
+	switch {
+	case len(cmd.Args) == 0:
+	case len(cmd.Args) == 2 && cmd.Args[0] == "--folder":
+		folderName = cmd.Args[1]
+	default:
+		return fmt.Errorf("usage: markallread [--folder <name>]")
+	}
+
+	folderID, err := folderFilter(s, user, folderName)
+	if err != nil {
+		return err
+	}
+
+	marked, err := s.DB.MarkAllPostsRead(context.Background(), database.MarkAllPostsReadParams{
+		UserID:   user.ID,
+		FolderID: folderID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark posts as read: %w", err)
+	}
+
+	fmt.Printf("Marked %d posts as read\n", marked)
+	return nil
+}
+
+func HandlerHistory(s *State, cmd Command) error {
+	if err := validateArgs(cmd.Args, 1, "history"); err != nil {
+		return err
+	}
+
+	postID, err := parsePostID(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	post, err := s.DB.GetPostByID(context.Background(), postID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("post '%s' does not exist", postID)
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	revisions, err := s.DB.GetPostRevisions(context.Background(), postID)
+	if err != nil {
+		return fmt.Errorf("failed to get revisions: %w", err)
+	}
+
+	fmt.Printf("Current (%s): %s\n", post.UpdatedAt.Format("2006-01-02 15:04"), post.Title)
+	if len(revisions) == 0 {
+		fmt.Println("The post has not been changed since it was first saved")
+		return nil
+	}
+
+	for _, revision := range revisions {
+		fmt.Printf("\n=== Replaced %s ===\n", revision.CreatedAt.Format("2006-01-02 15:04"))
+		fmt.Printf("Title: %s\n", revision.Title)
+		if revision.Url != "" {
+			fmt.Printf("URL: %s\n", revision.Url)
+		}
+		fmt.Printf("Description:\n%s\n", renderPlainText(revision.Description.String, plainTextWidth))
+	}
+	return nil
+}
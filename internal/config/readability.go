@@ -0,0 +1,426 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/BabichevDima/aggregator/internal/database"
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// minArticleLength is the amount of text below which an extraction is
+// considered a failure rather than the article
+const minArticleLength = 200
+
+var (
+	// class and id values of page furniture that never holds the article
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|foot|header|legends|menu|modal|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|ad-break|agegate|pagination|pager|popup|share|subscribe|newsletter`)
+	// ... unless they also look like content
+	maybeCandidate = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+
+	positiveWeight = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	negativeWeight = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// pageFurniture are removed before scoring together with everything inside them
+var pageFurniture = map[string]bool{"nav": true, "aside": true, "footer": true, "header": true}
+
+// extractArticle finds the main content of an HTML page, the way browser
+// reader modes do: paragraphs score their ancestors by length and comma count,
+// the best scored element wins and brings along siblings that look related.
+// The result is sanitized HTML with URLs resolved against pageURL.
+func extractArticle(page []byte, contentType, pageURL string) (string, error) {
+	reader, err := charset.NewReader(bytes.NewReader(page), contentType)
+	if err != nil {
+		return "", fmt.Errorf("decoding page: %w", err)
+	}
+	doc, err := html.Parse(reader)
+	if err != nil {
+		return "", fmt.Errorf("parsing page: %w", err)
+	}
+
+	body := findElement(doc, "body")
+	if body == nil {
+		return "", fmt.Errorf("page has no body")
+	}
+	removeClutter(body)
+
+	scores := scoreParagraphs(body)
+	var top *html.Node
+	for node, score := range scores {
+		score *= 1 - linkDensity(node)
+		scores[node] = score
+		if top == nil || score > scores[top] {
+			top = node
+		}
+	}
+	if top == nil {
+		// Nothing looked like prose, an <article> is the next best guess
+		if top = findElement(body, "article"); top == nil {
+			return "", fmt.Errorf("no article content found")
+		}
+	}
+
+	var buf strings.Builder
+	for _, node := range articleNodes(top, scores) {
+		if err := html.Render(&buf, node); err != nil {
+			return "", fmt.Errorf("rendering article: %w", err)
+		}
+	}
+
+	article := buf.String()
+	if base, err := url.Parse(pageURL); err == nil {
+		article = resolveHTMLURLs(article, base)
+	}
+	article = sanitizeHTML(article)
+
+	if len(strings.TrimSpace(renderPlainText(article, plainTextWidth))) < minArticleLength {
+		return "", fmt.Errorf("no article content found")
+	}
+	return article, nil
+}
+
+// removeClutter drops scripts, navigation and elements whose class or id
+// marks them as page furniture. Lazy-loaded images get their real src.
+func removeClutter(node *html.Node) {
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.CommentNode {
+			node.RemoveChild(child)
+		} else if child.Type == html.ElementNode {
+			if droppedElements[child.Data] || pageFurniture[child.Data] || isUnlikelyCandidate(child) {
+				node.RemoveChild(child)
+			} else {
+				if child.Data == "img" {
+					fixLazyImage(child)
+				}
+				removeClutter(child)
+			}
+		}
+		child = next
+	}
+}
+
+func isUnlikelyCandidate(node *html.Node) bool {
+	if node.Data == "article" || node.Data == "main" || node.Data == "a" {
+		return false
+	}
+	match := attrValue(node, "class") + " " + attrValue(node, "id")
+	if strings.TrimSpace(match) == "" {
+		return false
+	}
+	return unlikelyCandidates.MatchString(match) && !maybeCandidate.MatchString(match)
+}
+
+func fixLazyImage(node *html.Node) {
+	for _, key := range []string{"data-src", "data-original", "data-lazy-src"} {
+		lazy := attrValue(node, key)
+		if lazy == "" {
+			continue
+		}
+		for i, attr := range node.Attr {
+			if attr.Namespace == "" && attr.Key == "src" {
+				node.Attr[i].Val = lazy
+				return
+			}
+		}
+		node.Attr = append(node.Attr, html.Attribute{Key: "src", Val: lazy})
+		return
+	}
+}
+
+// scoreParagraphs gives every parent of a paragraph-like element a score.
+// The parent gets the full paragraph score, the grandparent half of it.
+func scoreParagraphs(body *html.Node) map[*html.Node]float64 {
+	scores := make(map[*html.Node]float64)
+
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode {
+				walk(child)
+			}
+		}
+		if node.Type != html.ElementNode || (node.Data != "p" && node.Data != "pre" && node.Data != "td") {
+			return
+		}
+
+		text := strings.TrimSpace(textContent(node))
+		if len(text) < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text)/100), 3)
+
+		parent := node.Parent
+		if parent == nil || parent.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[parent]; !ok {
+			scores[parent] = initialScore(parent)
+		}
+		scores[parent] += score
+
+		grandparent := parent.Parent
+		if grandparent == nil || grandparent.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[grandparent]; !ok {
+			scores[grandparent] = initialScore(grandparent)
+		}
+		scores[grandparent] += score / 2
+	}
+	walk(body)
+
+	return scores
+}
+
+func initialScore(node *html.Node) float64 {
+	score := classWeight(node)
+	switch node.Data {
+	case "article", "main":
+		score += 10
+	case "div":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	return score
+}
+
+func classWeight(node *html.Node) float64 {
+	var weight float64
+	for _, value := range []string{attrValue(node, "class"), attrValue(node, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeWeight.MatchString(value) {
+			weight -= 25
+		}
+		if positiveWeight.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of an element's text that sits inside links
+func linkDensity(node *html.Node) float64 {
+	textLength := len(strings.TrimSpace(textContent(node)))
+	if textLength == 0 {
+		return 0
+	}
+
+	var linkLength int
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			linkLength += len(strings.TrimSpace(textContent(n)))
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+
+	return float64(linkLength) / float64(textLength)
+}
+
+// articleNodes returns top together with the siblings that belong to the
+// same article, such as an intro paragraph outside the main container
+func articleNodes(top *html.Node, scores map[*html.Node]float64) []*html.Node {
+	if top.Parent == nil {
+		return []*html.Node{top}
+	}
+
+	threshold := max(10, scores[top]*0.2)
+	var nodes []*html.Node
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling == top {
+			nodes = append(nodes, sibling)
+			continue
+		}
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		if score, ok := scores[sibling]; ok && score >= threshold {
+			nodes = append(nodes, sibling)
+			continue
+		}
+		if sibling.Data == "p" {
+			text := strings.TrimSpace(textContent(sibling))
+			density := linkDensity(sibling)
+			if (len(text) > 80 && density < 0.25) || (len(text) > 0 && density == 0 && strings.ContainsAny(text, ".!?")) {
+				nodes = append(nodes, sibling)
+			}
+		}
+	}
+	return nodes
+}
+
+func findElement(node *html.Node, tag string) *html.Node {
+	if node.Type == html.ElementNode && node.Data == tag {
+		return node
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// fetchArticle downloads a post's page and extracts the article from it
+func fetchArticle(ctx context.Context, fetcher *Fetcher, pageURL string) (string, error) {
+	res, err := fetcher.Get(ctx, pageURL)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+	contentType := res.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return "", fmt.Errorf("page is %s, not HTML", mediaType)
+	}
+
+	page, err := fetcher.ReadBody(res)
+	if err != nil {
+		return "", err
+	}
+	return extractArticle(page, contentType, res.Request.URL.String())
+}
+
+// saveArticle extracts the full article of a post and stores it with the post
+func saveArticle(s *State, postID uuid.UUID, pageURL string) (string, error) {
+	article, err := fetchArticle(context.Background(), s.Fetcher, pageURL)
+	if err != nil {
+		return "", err
+	}
+	if err := s.DB.SetPostArticle(context.Background(), database.SetPostArticleParams{
+		ID:             postID,
+		ArticleContent: nullString(article),
+	}); err != nil {
+		return "", fmt.Errorf("save article: %w", err)
+	}
+	return article, nil
+}
+
+// HandlerFullText turns article extraction on or off for a feed the user may modify
+func HandlerFullText(s *State, cmd Command, user database.User) error {
+	if err := validateArgs(cmd.Args, 2, "fulltext"); err != nil {
+		return err
+	}
+
+	var enabled bool
+	switch strings.ToLower(cmd.Args[1]) {
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		return fmt.Errorf("usage: fulltext <feed-url> <on|off>")
+	}
+
+	feed, err := feedToModify(s, user, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	feed, err = s.DB.SetFeedExtractFullText(context.Background(), database.SetFeedExtractFullTextParams{
+		ID:              feed.ID,
+		ExtractFullText: enabled,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update feed: %w", err)
+	}
+
+	if enabled {
+		fmt.Printf("Full articles will be extracted for new posts of %s\n", feed.Name)
+	} else {
+		fmt.Printf("Full article extraction disabled for %s\n", feed.Name)
+	}
+	return nil
+}
+
+func HandlerView(s *State, cmd Command, user database.User) error {
+	if err := validateArgs(cmd.Args, 1, "view"); err != nil {
+		return err
+	}
+
+	postID, err := parsePostID(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	post, err := s.DB.GetPostByID(context.Background(), postID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("post '%s' does not exist", postID)
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	feed, err := s.DB.GetFeedByID(context.Background(), post.FeedID)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	feedName, err := s.DB.GetFeedNameForUser(context.Background(), database.GetFeedNameForUserParams{
+		FeedID: feed.ID,
+		UserID: uuid.NullUUID{UUID: user.ID, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	text := post.ArticleContent.String
+	if !post.ArticleContent.Valid && feed.ExtractFullText && post.Url != "" {
+		// Posts saved before the feed opted in are extracted on first view
+		if text, err = saveArticle(s, post.ID, post.Url); err != nil {
+			fmt.Printf("Could not extract the article: %v\n", err)
+		}
+	}
+	if text == "" {
+		text = post.Content.String
+	}
+	if text == "" {
+		text = post.Description.String
+	}
+
+	fmt.Println(post.Title)
+	if post.Url != "" {
+		fmt.Println(post.Url)
+	}
+	fmt.Printf("Feed: %s\n", feedName)
+	if post.Author.Valid {
+		fmt.Printf("Author: %s\n", post.Author.String)
+	}
+	if post.PublishedAt.Valid {
+		fmt.Printf("Published: %s\n", post.PublishedAt.Time.Format("2006-01-02 15:04"))
+	}
+	fmt.Printf("\n%s\n", renderPlainText(text, plainTextWidth))
+
+	if err := s.DB.MarkPostRead(context.Background(), database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	}); err != nil {
+		return fmt.Errorf("failed to mark post as read: %w", err)
+	}
+	return nil
+}
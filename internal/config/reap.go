@@ -0,0 +1,202 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BabichevDima/aggregator/internal/database"
+)
+
+// Values of the orphan_action setting
+const (
+	orphanActionDelete  = "delete"
+	orphanActionArchive = "archive"
+)
+
+const defaultOrphanGraceDays = 30
+
+func orphanGracePeriod(cfg *Config) time.Duration {
+	days := cfg.OrphanGraceDays
+	if days <= 0 {
+		days = defaultOrphanGraceDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func orphanAction(cfg *Config) (string, error) {
+	switch cfg.OrphanAction {
+	case "", orphanActionDelete:
+		return orphanActionDelete, nil
+	case orphanActionArchive:
+		return orphanActionArchive, nil
+	}
+	return "", fmt.Errorf("invalid orphan_action '%s', use \"delete\" or \"archive\"", cfg.OrphanAction)
+}
+
+// archivedFeed is a reaped feed as written to the archive directory
+type archivedFeed struct {
+	Name        string         `json:"name"`
+	URL         string         `json:"url"`
+	SiteURL     string         `json:"site_url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	OrphanedAt  time.Time      `json:"orphaned_at"`
+	Posts       []archivedPost `json:"posts"`
+}
+
+type archivedPost struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url,omitempty"`
+	Author      string     `json:"author,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Content     string     `json:"content,omitempty"`
+	Snapshot    string     `json:"snapshot,omitempty"`
+}
+
+// archiveFeed writes a feed and its posts to feeds/ in the archive directory
+// and returns the file's path
+func archiveFeed(s *State, feed database.GetOrphanedFeedsRow) (string, error) {
+	dir, err := archiveDir(s.Config)
+	if err != nil {
+		return "", err
+	}
+	posts, err := s.DB.GetPostsForFeed(context.Background(), feed.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get posts: %w", err)
+	}
+
+	archived := archivedFeed{
+		Name:        feed.Name,
+		URL:         feed.Url,
+		SiteURL:     feed.SiteUrl.String,
+		Description: feed.Description.String,
+		OrphanedAt:  feed.OrphanedAt.Time,
+		Posts:       make([]archivedPost, 0, len(posts)),
+	}
+	for _, post := range posts {
+		item := archivedPost{
+			ID:       post.ID.String(),
+			Title:    post.Title,
+			URL:      post.Url,
+			Author:   post.Author.String,
+			Content:  post.ArticleContent.String,
+			Snapshot: post.Snapshot.String,
+		}
+		if item.Content == "" {
+			item.Content = post.Content.String
+		}
+		if item.Content == "" {
+			item.Content = post.Description.String
+		}
+		if post.PublishedAt.Valid {
+			item.PublishedAt = &post.PublishedAt.Time
+		}
+		archived.Posts = append(archived.Posts, item)
+	}
+
+	data, err := json.MarshalIndent(archived, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to build JSON: %w", err)
+	}
+	dest := filepath.Join(dir, "feeds", fmt.Sprintf("%s-%s.json", time.Now().Format("2006-01-02"), feed.ID))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", fmt.Errorf("create archive directory: %w", err)
+	}
+	if err := os.WriteFile(dest, data, 0644); err != nil {
+		return "", fmt.Errorf("write archive: %w", err)
+	}
+	return dest, nil
+}
+
+// reapFeed archives the feed if configured, then deletes it with its posts.
+// Starred and tagged posts are kept, and so is the feed while it still has some.
+func reapFeed(s *State, feed database.GetOrphanedFeedsRow, action string) error {
+	ctx := context.Background()
+	if action == orphanActionArchive {
+		dest, err := archiveFeed(s, feed)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Archived '%s' to %s\n", feed.Name, dest)
+	}
+
+	if feed.SavedCount > 0 {
+		if _, err := s.DB.DeleteUnsavedPosts(ctx, feed.ID); err != nil {
+			return fmt.Errorf("failed to delete posts: %w", err)
+		}
+		return nil
+	}
+	if err := s.DB.DeleteFeed(ctx, feed.ID); err != nil {
+		return fmt.Errorf("failed to delete feed: %w", err)
+	}
+	return nil
+}
+
+// HandlerReap deletes (or archives) feeds nobody has followed for the grace
+// period and reports the ones still waiting: reap [--dry-run]
+func HandlerReap(s *State, cmd Command) error {
+	dryRun := false
+	for _, arg := range cmd.Args {
+		if arg != "--dry-run" {
+			return fmt.Errorf("usage: reap [--dry-run]")
+		}
+		dryRun = true
+	}
+
+	action, err := orphanAction(s.Config)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	if err := s.DB.MarkOrphanedFeeds(ctx); err != nil {
+		return fmt.Errorf("failed to update orphaned feeds: %w", err)
+	}
+	feeds, err := s.DB.GetOrphanedFeeds(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get orphaned feeds: %w", err)
+	}
+	if len(feeds) == 0 {
+		fmt.Println("Every feed has followers")
+		return nil
+	}
+
+	grace := orphanGracePeriod(s.Config)
+	reaped := 0
+	for _, feed := range feeds {
+		due := feed.OrphanedAt.Time.Add(grace)
+		if time.Now().Before(due) {
+			fmt.Printf("%s: no followers since %s, reaped after %s\n",
+				feed.Name, feed.OrphanedAt.Time.Format("2006-01-02"), due.Format("2006-01-02"))
+			continue
+		}
+
+		// Already reaped down to its starred and tagged posts
+		if feed.SavedCount > 0 && feed.PostCount == feed.SavedCount {
+			continue
+		}
+
+		if dryRun {
+			fmt.Printf("%s: would %s, %d posts (%d starred or tagged kept)\n", feed.Name, action, feed.PostCount, feed.SavedCount)
+			reaped++
+			continue
+		}
+		if err := reapFeed(s, feed, action); err != nil {
+			fmt.Printf("Error reaping '%s': %v\n", feed.Name, err)
+			continue
+		}
+		fmt.Printf("%s: reaped %d posts (%d starred or tagged kept)\n", feed.Name, feed.PostCount-feed.SavedCount, feed.SavedCount)
+		reaped++
+	}
+
+	if dryRun {
+		fmt.Printf("%d feeds would be reaped\n", reaped)
+	} else {
+		fmt.Printf("Reaped %d feeds\n", reaped)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BabichevDima/aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// prunePosts deletes the posts the retention settings no longer keep, from
+// one feed or, with an invalid feedID, from all of them. With dryRun it only
+// reports them. Starred and tagged posts are always kept.
+func prunePosts(s *State, feedID uuid.NullUUID, dryRun bool) ([]database.GetPrunablePostsRow, error) {
+	ctx := context.Background()
+	posts, err := s.DB.GetPrunablePosts(ctx, database.GetPrunablePostsParams{
+		KeepUnread:     s.Config.RetentionKeepUnread,
+		RetentionDays:  int32(s.Config.RetentionDays),
+		RetentionItems: int32(s.Config.RetentionItems),
+		FeedID:         feedID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find posts to prune: %w", err)
+	}
+	if dryRun || len(posts) == 0 {
+		return posts, nil
+	}
+
+	ids := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	if _, err := s.DB.PrunePosts(ctx, ids); err != nil {
+		return nil, fmt.Errorf("failed to prune posts: %w", err)
+	}
+	return posts, nil
+}
+
+func HandlerPrune(s *State, cmd Command) error {
+	dryRun := false
+	for _, arg := range cmd.Args {
+		if arg != "--dry-run" {
+			return fmt.Errorf("usage: prune [--dry-run]")
+		}
+		dryRun = true
+	}
+
+	posts, err := prunePosts(s, uuid.NullUUID{}, dryRun)
+	if err != nil {
+		return err
+	}
+	if len(posts) == 0 {
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+
+	// Posts come ordered by feed, report one line per feed
+	for i := 0; i < len(posts); {
+		j := i
+		for j < len(posts) && posts[j].FeedID == posts[i].FeedID {
+			j++
+		}
+		fmt.Printf("%s: %d posts\n", posts[i].FeedName, j-i)
+		i = j
+	}
+
+	if dryRun {
+		fmt.Printf("%d posts would be pruned\n", len(posts))
+	} else {
+		fmt.Printf("Pruned %d posts\n", len(posts))
+	}
+	return nil
+}
+
+// parseRetentionValue reads a days=/items= value: a number, or "default"
+// to fall back to the config
+func parseRetentionValue(key, value string) (sql.NullInt32, error) {
+	if value == "default" {
+		return sql.NullInt32{}, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return sql.NullInt32{}, fmt.Errorf("invalid %s '%s': use a number, 0 for no limit, or default", key, value)
+	}
+	return sql.NullInt32{Int32: int32(n), Valid: true}, nil
+}
+
+func formatRetention(value sql.NullInt32, global int, unit string) string {
+	switch {
+	case !value.Valid && global == 0:
+		return "no limit (default)"
+	case !value.Valid:
+		return fmt.Sprintf("%d %s (default)", global, unit)
+	case value.Int32 == 0:
+		return "no limit"
+	}
+	return fmt.Sprintf("%d %s", value.Int32, unit)
+}
+
+// HandlerRetention shows a feed's retention, which only the user who added the
+// feed or an admin may change, as it deletes posts for every follower
+func HandlerRetention(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("usage: retention <feed-url> [days=N] [items=N]")
+	}
+
+	feed, err := getFeedByURL(context.Background(), s.DB, cmd.Args[0])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("feed with URL '%s' does not exist", cmd.Args[0])
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	if len(cmd.Args) > 1 {
+		if err := canModifyFeed(user, feed); err != nil {
+			return err
+		}
+		params := database.SetFeedRetentionParams{
+			Url:            feed.Url,
+			RetentionDays:  feed.RetentionDays,
+			RetentionItems: feed.RetentionItems,
+		}
+		for _, arg := range cmd.Args[1:] {
+			key, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				return fmt.Errorf("invalid setting '%s', expected key=value", arg)
+			}
+			switch key {
+			case "days":
+				params.RetentionDays, err = parseRetentionValue(key, value)
+			case "items":
+				params.RetentionItems, err = parseRetentionValue(key, value)
+			default:
+				return fmt.Errorf("unknown setting '%s', use days or items", key)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		feed, err = s.DB.SetFeedRetention(context.Background(), params)
+		if err != nil {
+			return fmt.Errorf("failed to update feed: %w", err)
+		}
+	}
+
+	fmt.Printf("Retention for %s:\n", feed.Name)
+	fmt.Printf("  Age: %s\n", formatRetention(feed.RetentionDays, s.Config.RetentionDays, "days"))
+	fmt.Printf("  Posts: %s\n", formatRetention(feed.RetentionItems, s.Config.RetentionItems, "newest"))
+	return nil
+}
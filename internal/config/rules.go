@@ -0,0 +1,432 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/BabichevDima/aggregator/internal/database"
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+)
+
+// Rule fields, match types and actions
+const (
+	ruleFieldTitle       = "title"
+	ruleFieldDescription = "description"
+	ruleFieldAuthor      = "author"
+	ruleFieldFeed        = "feed"
+	ruleFieldCategory    = "category"
+	ruleFieldAny         = "any"
+
+	ruleMatchContains = "contains"
+	ruleMatchRegex    = "regex"
+
+	ruleActionHide   = "hide"
+	ruleActionRead   = "read"
+	ruleActionStar   = "star"
+	ruleActionTag    = "tag"
+	ruleActionNotify = "notify"
+)
+
+const ruleUsage = `usage: rule add <title|description|author|feed|category|any> <contains|regex> <pattern> <hide|read|star|tag <tag>|notify>
+       rule list | rule remove <rule-id> | rule test <post-id>`
+
+// browseHiddenPageLimit caps how far browse looks past hidden and duplicate posts to fill a page
+const browseHiddenPageLimit = 10000
+
+// ruleSubject is the text of a post that rules look at
+type ruleSubject struct {
+	Title       string
+	Description string
+	Author      string
+	Feed        string
+	Categories  []string
+}
+
+// htmlText is the text of an HTML fragment without any markup
+func htmlText(fragment string) string {
+	if !strings.Contains(fragment, "<") {
+		return fragment
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), bodyContext)
+	if err != nil {
+		return fragment
+	}
+	var buf strings.Builder
+	for _, node := range nodes {
+		buf.WriteString(textContent(node))
+		buf.WriteString(" ")
+	}
+	return buf.String()
+}
+
+// ruleMatcher evaluates rules, compiling each regex only once
+type ruleMatcher struct {
+	regexps map[string]*regexp.Regexp
+}
+
+func newRuleMatcher() *ruleMatcher {
+	return &ruleMatcher{regexps: make(map[string]*regexp.Regexp)}
+}
+
+func (m *ruleMatcher) matchText(rule database.Rule, text string) bool {
+	if rule.MatchType == ruleMatchRegex {
+		re, ok := m.regexps[rule.Pattern]
+		if !ok {
+			// Patterns are validated when the rule is added
+			re, _ = regexp.Compile(rule.Pattern)
+			m.regexps[rule.Pattern] = re
+		}
+		return re != nil && re.MatchString(text)
+	}
+	return strings.Contains(strings.ToLower(text), strings.ToLower(rule.Pattern))
+}
+
+func (m *ruleMatcher) matches(rule database.Rule, subject ruleSubject) bool {
+	var texts []string
+	switch rule.Field {
+	case ruleFieldTitle:
+		texts = []string{subject.Title}
+	case ruleFieldDescription:
+		texts = []string{subject.Description}
+	case ruleFieldAuthor:
+		texts = []string{subject.Author}
+	case ruleFieldFeed:
+		texts = []string{subject.Feed}
+	case ruleFieldCategory:
+		texts = subject.Categories
+	case ruleFieldAny:
+		texts = append([]string{subject.Title, subject.Description, subject.Author, subject.Feed}, subject.Categories...)
+	}
+
+	for _, text := range texts {
+		if text != "" && m.matchText(rule, text) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatRule(rule database.Rule) string {
+	action := rule.Action
+	if rule.ActionArg.Valid {
+		action += " " + rule.ActionArg.String
+	}
+	return fmt.Sprintf("%s %s %q -> %s", rule.Field, rule.MatchType, rule.Pattern, action)
+}
+
+// applyIngestRules notifies the followers of feed who asked for it about a new
+// post and runs their rules against it. Hiding is left to browse, which also
+// applies hide rules to older posts; here they only stop notifications.
+func applyIngestRules(s *State, feed database.Feed, postID uuid.UUID, item RSSItem) error {
+	ctx := context.Background()
+	rows, err := s.DB.GetRulesForFeed(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get rules: %w", err)
+	}
+
+	matcher := newRuleMatcher()
+	var matched []database.Rule
+	hidden := make(map[uuid.UUID]bool)
+	// Followers may have renamed the feed
+	feedNames := make(map[uuid.UUID]string)
+	for _, row := range rows {
+		feedNames[row.UserID] = row.FeedName
+		rule := database.Rule{
+			ID:        row.ID,
+			UserID:    row.UserID,
+			Field:     row.Field,
+			MatchType: row.MatchType,
+			Pattern:   row.Pattern,
+			Action:    row.Action,
+			ActionArg: row.ActionArg,
+		}
+		subject := ruleSubject{
+			Title:       item.Title,
+			Description: htmlText(item.Description + " " + item.Content),
+			Author:      item.PostAuthor(),
+			Feed:        row.FeedName,
+			Categories:  item.Categories,
+		}
+		if !matcher.matches(rule, subject) {
+			continue
+		}
+		if rule.Action == ruleActionHide {
+			hidden[rule.UserID] = true
+		}
+		matched = append(matched, rule)
+	}
+
+	hiddenFor := make([]uuid.UUID, 0, len(hidden))
+	for userID := range hidden {
+		hiddenFor = append(hiddenFor, userID)
+	}
+	if err := s.DB.NotifyNewPost(ctx, database.NotifyNewPostParams{
+		PostID:    uuid.NullUUID{UUID: postID, Valid: true},
+		Title:     item.Title,
+		FeedID:    feed.ID,
+		HiddenFor: hiddenFor,
+	}); err != nil {
+		return fmt.Errorf("failed to create notifications: %w", err)
+	}
+
+	for _, rule := range matched {
+		switch rule.Action {
+		case ruleActionRead:
+			err = s.DB.MarkPostRead(ctx, database.MarkPostReadParams{UserID: rule.UserID, PostID: postID})
+		case ruleActionStar:
+			err = s.DB.StarPost(ctx, database.StarPostParams{UserID: rule.UserID, PostID: postID})
+		case ruleActionTag:
+			err = s.DB.TagPost(ctx, database.TagPostParams{UserID: rule.UserID, PostID: postID, Tag: rule.ActionArg.String})
+		case ruleActionNotify:
+			if hidden[rule.UserID] {
+				continue
+			}
+			err = s.DB.CreateNotification(ctx, database.CreateNotificationParams{
+				UserID:  rule.UserID,
+				FeedID:  uuid.NullUUID{UUID: feed.ID, Valid: true},
+				PostID:  uuid.NullUUID{UUID: postID, Valid: true},
+				Message: fmt.Sprintf("%s: %s (rule: %s)", feedNames[rule.UserID], item.Title, formatRule(rule)),
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply rule %s: %w", rule.ID, err)
+		}
+	}
+	return nil
+}
+
+func postRowSubject(post database.GetPostsForUserRow) ruleSubject {
+	subject := ruleSubject{
+		Title:       post.Title,
+		Description: htmlText(post.Description.String + " " + post.Content.String),
+		Author:      post.Author.String,
+		Feed:        post.FeedName,
+	}
+	if post.Categories != "" {
+		subject.Categories = strings.Split(post.Categories, ", ")
+	}
+	return subject
+}
+
+func getHideRules(s *State, user database.User) ([]database.Rule, error) {
+	rules, err := s.DB.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rules: %w", err)
+	}
+	var hideRules []database.Rule
+	for _, rule := range rules {
+		if rule.Action == ruleActionHide {
+			hideRules = append(hideRules, rule)
+		}
+	}
+	return hideRules, nil
+}
+
+// hides reports whether any of hideRules matches the post
+func (m *ruleMatcher) hides(hideRules []database.Rule, subject ruleSubject) bool {
+	for _, rule := range hideRules {
+		if m.matches(rule, subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// getPostClusters is getPosts without the posts the user's hide rules match,
+// and with copies of the same story from several feeds shown once. It keeps
+// reading further until the page is full or the posts run out.
+func getPostClusters(s *State, user database.User, opts browseOptions) ([]postCluster, error) {
+	hideRules, err := getHideRules(s, user)
+	if err != nil {
+		return nil, err
+	}
+	matcher := newRuleMatcher()
+	want := int(opts.Limit)
+	for {
+		posts, err := getPosts(s, user, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		visible := make([]database.GetPostsForUserRow, 0, len(posts))
+		for _, post := range posts {
+			if !matcher.hides(hideRules, postRowSubject(post)) {
+				visible = append(visible, post)
+			}
+		}
+
+		clusters := clusterPosts(visible)
+		if len(clusters) >= want || len(posts) < int(opts.Limit) || opts.Limit >= browseHiddenPageLimit {
+			if len(clusters) > want {
+				clusters = clusters[:want]
+			}
+			return clusters, nil
+		}
+		opts.Limit = min(opts.Limit*2+int32(len(posts)-len(clusters)), browseHiddenPageLimit)
+	}
+}
+
+func HandlerRule(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) == 0 {
+		return errors.New(ruleUsage)
+	}
+	ctx := context.Background()
+	args := cmd.Args[1:]
+
+	switch cmd.Args[0] {
+	case "add":
+		if len(args) < 4 || len(args) > 5 {
+			return errors.New(ruleUsage)
+		}
+		field, matchType, pattern, action := args[0], args[1], args[2], args[3]
+
+		switch field {
+		case ruleFieldTitle, ruleFieldDescription, ruleFieldAuthor, ruleFieldFeed, ruleFieldCategory, ruleFieldAny:
+		default:
+			return fmt.Errorf("unknown field '%s'", field)
+		}
+		switch matchType {
+		case ruleMatchContains:
+		case ruleMatchRegex:
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid regex: %w", err)
+			}
+		default:
+			return fmt.Errorf("unknown match type '%s', use contains or regex", matchType)
+		}
+		if pattern == "" {
+			return fmt.Errorf("pattern cannot be empty")
+		}
+
+		var actionArg sql.NullString
+		switch action {
+		case ruleActionHide, ruleActionRead, ruleActionStar, ruleActionNotify:
+			if len(args) == 5 {
+				return errors.New(ruleUsage)
+			}
+		case ruleActionTag:
+			if len(args) != 5 || len(parseTags(args[4])) != 1 {
+				return fmt.Errorf("the tag action needs one tag")
+			}
+			actionArg = nullString(parseTags(args[4])[0])
+		default:
+			return fmt.Errorf("unknown action '%s'", action)
+		}
+
+		rule, err := s.DB.CreateRule(ctx, database.CreateRuleParams{
+			ID:        uuid.New(),
+			UserID:    user.ID,
+			Field:     field,
+			MatchType: matchType,
+			Pattern:   pattern,
+			Action:    action,
+			ActionArg: actionArg,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add rule: %w", err)
+		}
+		fmt.Printf("Rule %s added: %s\n", rule.ID, formatRule(rule))
+
+	case "list":
+		if err := validateArgs(args, 0, "rule list"); err != nil {
+			return err
+		}
+		rules, err := s.DB.GetRulesForUser(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get rules: %w", err)
+		}
+		if len(rules) == 0 {
+			fmt.Println("No rules yet")
+		}
+		for _, rule := range rules {
+			fmt.Printf("%s  %s\n", rule.ID, formatRule(rule))
+		}
+
+	case "remove":
+		if err := validateArgs(args, 1, "rule remove"); err != nil {
+			return err
+		}
+		ruleID, err := uuid.Parse(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid rule id '%s'", args[0])
+		}
+		removed, err := s.DB.DeleteRule(ctx, database.DeleteRuleParams{ID: ruleID, UserID: user.ID})
+		if err != nil {
+			return fmt.Errorf("failed to remove rule: %w", err)
+		}
+		if removed == 0 {
+			return fmt.Errorf("rule '%s' does not exist", ruleID)
+		}
+		fmt.Printf("Rule %s removed\n", ruleID)
+
+	case "test":
+		if err := validateArgs(args, 1, "rule test"); err != nil {
+			return err
+		}
+		return testRules(s, user, args[0])
+
+	default:
+		return errors.New(ruleUsage)
+	}
+	return nil
+}
+
+// testRules shows which of the user's rules match a post
+func testRules(s *State, user database.User, arg string) error {
+	ctx := context.Background()
+	postID, err := parsePostID(arg)
+	if err != nil {
+		return err
+	}
+
+	post, err := s.DB.GetPostByID(ctx, postID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("post '%s' does not exist", postID)
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+	feed, err := s.DB.GetFeedByID(ctx, post.FeedID)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	categories, err := s.DB.GetPostCategories(ctx, post.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	feedName := feed.Name
+	if follow, err := s.DB.GetFeedFollow(ctx, database.GetFeedFollowParams{UserID: user.ID, FeedID: feed.ID}); err == nil && follow.TitleOverride.Valid {
+		feedName = follow.TitleOverride.String
+	}
+	subject := ruleSubject{
+		Title:       post.Title,
+		Description: htmlText(post.Description.String + " " + post.Content.String),
+		Author:      post.Author.String,
+		Feed:        feedName,
+		Categories:  categories,
+	}
+
+	rules, err := s.DB.GetRulesForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get rules: %w", err)
+	}
+
+	matcher := newRuleMatcher()
+	matched := 0
+	for _, rule := range rules {
+		if matcher.matches(rule, subject) {
+			fmt.Printf("match     %s  %s\n", rule.ID, formatRule(rule))
+			matched++
+		} else {
+			fmt.Printf("no match  %s  %s\n", rule.ID, formatRule(rule))
+		}
+	}
+	fmt.Printf("%d of %d rules match '%s'\n", matched, len(rules), post.Title)
+	return nil
+}
@@ -0,0 +1,429 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// plainTextWidth is the line width used when printing posts in the terminal
+const plainTextWidth = 80
+
+// bodyContext parses feed HTML as the content of a <body>
+var bodyContext = &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+
+// droppedElements are removed together with everything inside them
+var droppedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "head": true, "title": true,
+	"iframe": true, "object": true, "embed": true, "applet": true, "frame": true, "frameset": true,
+	"form": true, "input": true, "button": true, "select": true, "textarea": true,
+	"svg": true, "math": true, "link": true, "meta": true, "base": true,
+}
+
+// allowedElements lists the tags kept by sanitizeHTML and the attributes allowed on each.
+// Any other element is unwrapped: the tag goes, its children stay.
+var allowedElements = map[string]map[string]bool{
+	"a":          {"href": true, "title": true},
+	"abbr":       {"title": true},
+	"b":          {},
+	"blockquote": {"cite": true},
+	"br":         {},
+	"code":       {},
+	"dd":         {},
+	"del":        {},
+	"div":        {},
+	"dl":         {},
+	"dt":         {},
+	"em":         {},
+	"figcaption": {},
+	"figure":     {},
+	"h1":         {},
+	"h2":         {},
+	"h3":         {},
+	"h4":         {},
+	"h5":         {},
+	"h6":         {},
+	"hr":         {},
+	"i":          {},
+	"img":        {"src": true, "alt": true, "title": true, "width": true, "height": true},
+	"ins":        {},
+	"li":         {},
+	"ol":         {"start": true},
+	"p":          {},
+	"pre":        {},
+	"q":          {"cite": true},
+	"s":          {},
+	"small":      {},
+	"span":       {},
+	"strong":     {},
+	"sub":        {},
+	"sup":        {},
+	"table":      {},
+	"tbody":      {},
+	"td":         {"colspan": true, "rowspan": true},
+	"tfoot":      {},
+	"th":         {"colspan": true, "rowspan": true},
+	"thead":      {},
+	"tr":         {},
+	"u":          {},
+	"ul":         {},
+}
+
+// urlAttributes must point at a safe scheme
+var urlAttributes = map[string]bool{"href": true, "src": true, "cite": true}
+
+var voidElements = map[string]bool{"br": true, "hr": true, "img": true}
+
+// sanitizeHTML keeps only allowlisted markup, so feed HTML can be served to a browser
+func sanitizeHTML(fragment string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), bodyContext)
+	if err != nil {
+		return html.EscapeString(fragment)
+	}
+
+	var buf strings.Builder
+	for _, node := range nodes {
+		writeSafeHTML(&buf, node)
+	}
+	return buf.String()
+}
+
+func writeSafeHTML(buf *strings.Builder, node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		buf.WriteString(html.EscapeString(node.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if droppedElements[node.Data] {
+		return
+	}
+
+	allowedAttrs, allowed := allowedElements[node.Data]
+	if !allowed {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			writeSafeHTML(buf, child)
+		}
+		return
+	}
+
+	buf.WriteString("<" + node.Data)
+	for _, attr := range node.Attr {
+		if attr.Namespace != "" || !allowedAttrs[attr.Key] {
+			continue
+		}
+		if urlAttributes[attr.Key] && !isSafeURL(attr.Val) {
+			continue
+		}
+		fmt.Fprintf(buf, ` %s="%s"`, attr.Key, html.EscapeString(attr.Val))
+	}
+	if node.Data == "a" {
+		buf.WriteString(` rel="nofollow noopener noreferrer"`)
+	}
+	buf.WriteString(">")
+
+	if voidElements[node.Data] {
+		return
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		writeSafeHTML(buf, child)
+	}
+	buf.WriteString("</" + node.Data + ">")
+}
+
+// isSafeURL accepts relative URLs and http, https and mailto ones
+func isSafeURL(raw string) bool {
+	// Browsers ignore whitespace and control characters inside the scheme
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, raw)
+
+	parsed, err := url.Parse(cleaned)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "", "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// renderPlainText turns feed HTML into readable terminal text: paragraphs
+// wrapped to width, markdown-style headings, lists and quotes, and links
+// replaced by [n] markers with the URLs listed as footnotes.
+func renderPlainText(fragment string, width int) string {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), bodyContext)
+	if err != nil {
+		return fragment
+	}
+
+	r := &plainTextRenderer{width: width}
+	for _, node := range nodes {
+		r.walk(node)
+	}
+	r.flush()
+
+	var text strings.Builder
+	for i, block := range r.blocks {
+		if i > 0 {
+			// list items stay on consecutive lines
+			if block.inList && r.blocks[i-1].inList {
+				text.WriteString("\n")
+			} else {
+				text.WriteString("\n\n")
+			}
+		}
+		text.WriteString(block.text)
+	}
+	if len(r.links) > 0 {
+		var footnotes []string
+		for i, link := range r.links {
+			footnotes = append(footnotes, fmt.Sprintf("[%d] %s", i+1, link))
+		}
+		text.WriteString("\n\n" + strings.Join(footnotes, "\n"))
+	}
+	return text.String()
+}
+
+type textBlock struct {
+	text   string
+	inList bool
+}
+
+type plainTextRenderer struct {
+	width     int
+	blocks    []textBlock
+	links     []string
+	listDepth int
+
+	// current paragraph
+	paragraph    strings.Builder
+	pendingSpace bool
+	prefix       string // "> " for quotes, indentation for nested lists
+	marker       string // list bullet of the current item
+}
+
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "header": true, "footer": true,
+	"aside": true, "nav": true, "main": true, "figure": true, "figcaption": true, "table": true,
+	"tr": true, "dl": true, "dt": true, "dd": true, "address": true, "details": true, "summary": true,
+}
+
+func (r *plainTextRenderer) walk(node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		r.text(node.Data)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if droppedElements[node.Data] {
+		return
+	}
+
+	switch node.Data {
+	case "br":
+		r.paragraph.WriteString("\n")
+		r.pendingSpace = false
+	case "img":
+		alt := strings.TrimSpace(attrValue(node, "alt"))
+		if alt == "" {
+			r.text("[image]")
+		} else {
+			r.text("[image: " + alt + "]")
+		}
+		r.pendingSpace = true
+	case "a":
+		r.walkChildren(node)
+		href := strings.TrimSpace(attrValue(node, "href"))
+		if href != "" && isSafeURL(href) && !strings.HasPrefix(href, "#") {
+			r.links = append(r.links, href)
+			r.inline(fmt.Sprintf("[%d]", len(r.links)))
+		}
+	case "pre":
+		r.flush()
+		var lines []string
+		for _, line := range strings.Split(strings.Trim(textContent(node), "\n"), "\n") {
+			lines = append(lines, r.prefix+"    "+line)
+		}
+		r.addBlock(strings.Join(lines, "\n"))
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.flush()
+		r.paragraph.WriteString(strings.Repeat("#", int(node.Data[1]-'0')) + " ")
+		r.walkChildren(node)
+		r.flush()
+	case "blockquote":
+		r.flush()
+		saved := r.prefix
+		r.prefix += "> "
+		r.walkChildren(node)
+		r.flush()
+		r.prefix = saved
+	case "ul", "ol":
+		r.flush()
+		r.listDepth++
+		saved, savedMarker := r.prefix, r.marker
+		if r.marker != "" {
+			r.prefix += strings.Repeat(" ", utf8.RuneCountInString(r.marker))
+		}
+		number := 1
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode || child.Data != "li" {
+				r.walk(child)
+				continue
+			}
+			r.flush()
+			r.marker = "- "
+			if node.Data == "ol" {
+				r.marker = fmt.Sprintf("%d. ", number)
+				number++
+			}
+			r.walkChildren(child)
+			r.flush()
+		}
+		r.marker = strings.Repeat(" ", utf8.RuneCountInString(savedMarker))
+		r.prefix = saved
+		r.listDepth--
+	case "hr":
+		r.flush()
+		r.addBlock(r.prefix + "---")
+	case "td", "th":
+		r.walkChildren(node)
+		r.inline(" |")
+	default:
+		if blockElements[node.Data] {
+			r.flush()
+			r.walkChildren(node)
+			r.flush()
+			return
+		}
+		r.walkChildren(node)
+	}
+}
+
+func (r *plainTextRenderer) walkChildren(node *html.Node) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		r.walk(child)
+	}
+}
+
+// text adds a text node, collapsing whitespace the way a browser would
+func (r *plainTextRenderer) text(data string) {
+	if data == "" {
+		return
+	}
+	startsWithSpace := strings.TrimLeft(data, " \t\r\n\f") != data
+	words := strings.Fields(data)
+	if len(words) == 0 {
+		r.pendingSpace = r.pendingSpace || startsWithSpace
+		return
+	}
+
+	if startsWithSpace {
+		r.pendingSpace = true
+	}
+	r.inline(strings.Join(words, " "))
+
+	endsWithSpace := strings.TrimRight(data, " \t\r\n\f") != data
+	r.pendingSpace = endsWithSpace
+}
+
+func (r *plainTextRenderer) inline(text string) {
+	current := r.paragraph.String()
+	if r.pendingSpace && current != "" && !strings.HasSuffix(current, "\n") && !strings.HasSuffix(current, " ") {
+		r.paragraph.WriteString(" ")
+	}
+	r.pendingSpace = false
+	r.paragraph.WriteString(text)
+}
+
+// flush finishes the current paragraph, wrapping it and adding the quote/list prefix
+func (r *plainTextRenderer) flush() {
+	text := strings.TrimSpace(r.paragraph.String())
+	r.paragraph.Reset()
+	r.pendingSpace = false
+	if text == "" {
+		return
+	}
+
+	first := r.prefix + r.marker
+	rest := r.prefix + strings.Repeat(" ", utf8.RuneCountInString(r.marker))
+	// The bullet belongs to the first paragraph of a list item only
+	r.marker = strings.Repeat(" ", utf8.RuneCountInString(r.marker))
+
+	var lines []string
+	for _, hardLine := range strings.Split(text, "\n") {
+		for _, line := range wrapText(strings.TrimSpace(hardLine), r.width-utf8.RuneCountInString(rest)) {
+			prefix := rest
+			if len(lines) == 0 {
+				prefix = first
+			}
+			lines = append(lines, prefix+line)
+		}
+	}
+	r.addBlock(strings.Join(lines, "\n"))
+}
+
+func (r *plainTextRenderer) addBlock(text string) {
+	r.blocks = append(r.blocks, textBlock{text: text, inList: r.listDepth > 0})
+}
+
+// wrapText breaks text into lines of at most width characters where possible
+func wrapText(text string, width int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+	if width <= 0 {
+		return []string{strings.Join(words, " ")}
+	}
+
+	var lines []string
+	line := words[0]
+	for _, word := range words[1:] {
+		if utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
+
+func attrValue(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Namespace == "" && attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func textContent(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var buf strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.Data == "br" {
+			buf.WriteString("\n")
+			continue
+		}
+		buf.WriteString(textContent(child))
+	}
+	return buf.String()
+}
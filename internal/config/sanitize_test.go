@@ -0,0 +1,271 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "allowed markup is kept",
+			in:   `<p>Hello <strong>world</strong></p>`,
+			want: `<p>Hello <strong>world</strong></p>`,
+		},
+		{
+			name: "links get rel",
+			in:   `<a href="https://example.com/a">x</a>`,
+			want: `<a href="https://example.com/a" rel="nofollow noopener noreferrer">x</a>`,
+		},
+		{
+			name: "relative and mailto links are kept",
+			in:   `<a href="/post">x</a><a href="mailto:me@example.com">y</a>`,
+			want: `<a href="/post" rel="nofollow noopener noreferrer">x</a><a href="mailto:me@example.com" rel="nofollow noopener noreferrer">y</a>`,
+		},
+		{
+			name: "javascript URL",
+			in:   `<a href="javascript:alert(1)">x</a>`,
+			want: `<a rel="nofollow noopener noreferrer">x</a>`,
+		},
+		{
+			name: "javascript URL in upper case",
+			in:   `<a href="JavaScript:alert(1)">x</a>`,
+			want: `<a rel="nofollow noopener noreferrer">x</a>`,
+		},
+		{
+			name: "javascript URL split by a tab",
+			in:   "<a href=\"java\tscript:alert(1)\">x</a>",
+			want: `<a rel="nofollow noopener noreferrer">x</a>`,
+		},
+		{
+			name: "javascript URL with leading space and entity",
+			in:   `<a href=" &#106;avascript:alert(1)">x</a>`,
+			want: `<a rel="nofollow noopener noreferrer">x</a>`,
+		},
+		{
+			name: "data URL in img",
+			in:   `<img src="data:text/html;base64,PHNjcmlwdD4=" alt="a">`,
+			want: `<img alt="a">`,
+		},
+		{
+			name: "vbscript URL in blockquote cite",
+			in:   `<blockquote cite="vbscript:msgbox(1)">q</blockquote>`,
+			want: `<blockquote>q</blockquote>`,
+		},
+		{
+			name: "event handlers",
+			in:   `<img src="https://example.com/a.png" onerror="alert(1)"><p onclick="alert(1)" onmouseover="x()">t</p>`,
+			want: `<img src="https://example.com/a.png"><p>t</p>`,
+		},
+		{
+			name: "style and class attributes",
+			in:   `<span style="position:fixed" class="x">t</span>`,
+			want: `<span>t</span>`,
+		},
+		{
+			name: "script is dropped with its content",
+			in:   `<p>a</p><script>alert(1)</script><p>b</p>`,
+			want: `<p>a</p><p>b</p>`,
+		},
+		{
+			name: "iframe, style and form are dropped",
+			in:   `<iframe src="https://evil.example"></iframe><style>p{}</style><form><input name="q"></form>ok`,
+			want: `ok`,
+		},
+		{
+			name: "svg with script is dropped",
+			in:   `<svg onload="alert(1)"><script>alert(1)</script></svg>ok`,
+			want: `ok`,
+		},
+		{
+			name: "unknown elements are unwrapped",
+			in:   `<article><font color="red">text</font></article>`,
+			want: `text`,
+		},
+		{
+			name: "text and attribute values are escaped",
+			in:   `<p title="x">1 &lt; 2 &amp; "q"</p><a title="&quot;&gt;&lt;script&gt;">x</a>`,
+			want: `<p>1 &lt; 2 &amp; &#34;q&#34;</p><a title="&#34;&gt;&lt;script&gt;" rel="nofollow noopener noreferrer">x</a>`,
+		},
+		{
+			name: "void elements",
+			in:   `a<br>b<hr>`,
+			want: `a<br>b<hr>`,
+		},
+		{
+			name: "comments are dropped",
+			in:   `a<!-- <script>alert(1)</script> -->b`,
+			want: `ab`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeHTML(tt.in); got != tt.want {
+				t.Errorf("sanitizeHTML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSafeURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"", true},
+		{"/relative/path", true},
+		{"relative", true},
+		{"#anchor", true},
+		{"http://example.com", true},
+		{"HTTPS://example.com", true},
+		{"mailto:me@example.com", true},
+		{"javascript:alert(1)", false},
+		{"JAVASCRIPT:alert(1)", false},
+		{" javascript:alert(1)", false},
+		{"java\nscript:alert(1)", false},
+		{"java\x00script:alert(1)", false},
+		{"vbscript:msgbox(1)", false},
+		{"data:text/html,<script>alert(1)</script>", false},
+		{"file:///etc/passwd", false},
+	}
+
+	for _, tt := range tests {
+		if got := isSafeURL(tt.in); got != tt.want {
+			t.Errorf("isSafeURL(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRenderPlainText(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		width int
+		want  string
+	}{
+		{
+			name:  "paragraphs are wrapped",
+			in:    `<p>one two three four five</p><p>six</p>`,
+			width: 10,
+			want:  "one two\nthree four\nfive\n\nsix",
+		},
+		{
+			name:  "whitespace is collapsed",
+			in:    "<p>  one\n\t<b>two</b>  three </p>",
+			width: 80,
+			want:  "one two three",
+		},
+		{
+			name:  "line breaks are kept",
+			in:    `<p>one<br>two</p>`,
+			width: 80,
+			want:  "one\ntwo",
+		},
+		{
+			name:  "headings",
+			in:    `<h1>Title</h1><p>Text</p><h3>Part</h3>`,
+			width: 80,
+			want:  "# Title\n\nText\n\n### Part",
+		},
+		{
+			name:  "lists",
+			in:    `<ul><li>one</li><li>two</li></ul><ol><li>first</li><li>second</li></ol>`,
+			width: 80,
+			want:  "- one\n- two\n1. first\n2. second",
+		},
+		{
+			name:  "nested lists are indented",
+			in:    `<ul><li>one<ol><li>inner</li><li>other</li></ol></li><li>two</li></ul>`,
+			width: 80,
+			want:  "- one\n  1. inner\n  2. other\n- two",
+		},
+		{
+			name:  "wrapped list items line up with the text",
+			in:    `<ul><li>one two three</li></ul>`,
+			width: 9,
+			want:  "- one two\n  three",
+		},
+		{
+			name:  "blockquotes are prefixed",
+			in:    `<blockquote><p>one two three</p><blockquote>deep</blockquote></blockquote>`,
+			width: 9,
+			want:  "> one two\n> three\n\n> > deep",
+		},
+		{
+			name:  "links become footnotes",
+			in:    `<p>See <a href="https://example.com/a">this</a> and <a href="https://example.com/b">that</a>.</p>`,
+			width: 80,
+			want:  "See this[1] and that[2].\n\n[1] https://example.com/a\n[2] https://example.com/b",
+		},
+		{
+			name:  "unsafe and anchor links get no footnote",
+			in:    `<a href="javascript:alert(1)">x</a> <a href="#top">y</a>`,
+			width: 80,
+			want:  "x y",
+		},
+		{
+			name:  "images show their alt text",
+			in:    `<p><img src="a.png" alt="A cat"> <img src="b.png"></p>`,
+			width: 80,
+			want:  "[image: A cat] [image]",
+		},
+		{
+			name:  "pre blocks are indented and not wrapped",
+			in:    "<p>Code:</p><pre>if x {\n  return one two three\n}</pre>",
+			width: 10,
+			want:  "Code:\n\n    if x {\n      return one two three\n    }",
+		},
+		{
+			name:  "pre in a blockquote",
+			in:    "<blockquote><pre>x := 1</pre></blockquote>",
+			width: 80,
+			want:  ">     x := 1",
+		},
+		{
+			name:  "scripts are dropped",
+			in:    `<p>Hi</p><script>alert(1)</script>`,
+			width: 80,
+			want:  "Hi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderPlainText(tt.in, tt.width); got != tt.want {
+				t.Errorf("renderPlainText(%q, %d) =\n%s\nwant\n%s", tt.in, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  []string
+	}{
+		{"fits", "one two", 10, []string{"one two"}},
+		{"exact width", "one two", 7, []string{"one two"}},
+		{"wraps", "one two three", 7, []string{"one two", "three"}},
+		{"long word stays whole", "a verylongword b", 5, []string{"a", "verylongword", "b"}},
+		{"collapses spaces", "  one \t two  ", 80, []string{"one two"}},
+		{"counts runes", "привет мир", 10, []string{"привет мир"}},
+		{"no width", "one two three", 0, []string{"one two three"}},
+		{"empty", "   ", 10, []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapText(tt.text, tt.width)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("wrapText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/BabichevDima/aggregator/internal/database"
+)
+
+// parseTags splits "go, Security" into normalized tags: go, security
+func parseTags(arg string) []string {
+	var tags []string
+	for _, tag := range strings.Split(arg, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func HandlerTag(s *State, cmd Command, user database.User) error {
+	if err := validateArgs(cmd.Args, 2, "tag"); err != nil {
+		return err
+	}
+
+	postID, err := parsePostID(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+	tags := parseTags(cmd.Args[1])
+	if len(tags) == 0 {
+		return fmt.Errorf("no tags given")
+	}
+
+	if _, err := s.DB.GetPostByID(context.Background(), postID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("post '%s' does not exist", postID)
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	for _, tag := range tags {
+		if err := s.DB.TagPost(context.Background(), database.TagPostParams{
+			UserID: user.ID,
+			PostID: postID,
+			Tag:    tag,
+		}); err != nil {
+			return fmt.Errorf("failed to tag post: %w", err)
+		}
+	}
+
+	fmt.Printf("Post %s tagged %s\n", postID, strings.Join(tags, ", "))
+	return nil
+}
+
+// HandlerUntag removes the given tags from a post, or all of them: untag <post-id> [tags]
+func HandlerUntag(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 1 || len(cmd.Args) > 2 {
+		return fmt.Errorf("usage: untag <post-id> [tag1,tag2]")
+	}
+
+	postID, err := parsePostID(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	var removed int64
+	if len(cmd.Args) == 1 {
+		removed, err = s.DB.UntagPostAll(context.Background(), database.UntagPostAllParams{
+			UserID: user.ID,
+			PostID: postID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to untag post: %w", err)
+		}
+	} else {
+		for _, tag := range parseTags(cmd.Args[1]) {
+			n, err := s.DB.UntagPost(context.Background(), database.UntagPostParams{
+				UserID: user.ID,
+				PostID: postID,
+				Tag:    tag,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to untag post: %w", err)
+			}
+			removed += n
+		}
+	}
+
+	fmt.Printf("Removed %d tags from post %s\n", removed, postID)
+	return nil
+}
+
+// HandlerTagged lists posts with a tag, or the user's tags when none is given:
+// tagged [<tag> [browse options]]
+func HandlerTagged(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) == 0 {
+		tags, err := s.DB.GetTagsForUser(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get tags: %w", err)
+		}
+		if len(tags) == 0 {
+			fmt.Println("No tagged posts yet")
+		}
+		for _, tag := range tags {
+			fmt.Printf("* %s (%d posts)\n", tag.Tag, tag.PostCount)
+		}
+		return nil
+	}
+
+	opts, err := parseBrowseArgs(cmd.Args[1:], 10)
+	if err != nil {
+		return err
+	}
+	opts.Tag = strings.ToLower(strings.TrimSpace(cmd.Args[0]))
+	return showPosts(s, user, opts)
+}
+
+// HandlerSearch finds posts whose title, text, author, categories or tags
+// contain the query: search <query> [browse options]
+func HandlerSearch(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) == 0 || strings.TrimSpace(cmd.Args[0]) == "" {
+		return fmt.Errorf("usage: search <query> [limit] [--folder <name>] [--tag <tag>] ...")
+	}
+
+	opts, err := parseBrowseArgs(cmd.Args[1:], 10)
+	if err != nil {
+		return err
+	}
+	opts.Query = strings.TrimSpace(cmd.Args[0])
+	return showPosts(s, user, opts)
+}
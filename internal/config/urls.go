@@ -0,0 +1,199 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// resolveFeedURLs makes every link of the feed absolute. Relative references are
+// resolved against xml:base when present, otherwise against the channel link
+// (the site), otherwise against the URL the feed was fetched from.
+func resolveFeedURLs(rssFeed *RSSFeed, feedURL string) {
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return
+	}
+	base = resolveBase(base, rssFeed.Base)
+	channelBase := resolveBase(base, rssFeed.Channel.Base)
+
+	rssFeed.Channel.Link = resolveReference(channelBase, rssFeed.Channel.Link)
+	rssFeed.Channel.Image.URL = resolveReference(channelBase, rssFeed.Channel.Image.URL)
+
+	itemBase := channelBase
+	if rssFeed.Base == "" && rssFeed.Channel.Base == "" {
+		if site, err := url.Parse(rssFeed.Channel.Link); err == nil && site.IsAbs() {
+			itemBase = site
+		}
+	}
+
+	for i := range rssFeed.Channel.Item {
+		item := &rssFeed.Channel.Item[i]
+		b := resolveBase(itemBase, item.Base)
+
+		item.RawLink = item.Link
+		item.Link = resolveReference(b, item.Link)
+		item.Comments = resolveReference(b, item.Comments)
+		item.ITunesImage.Href = resolveReference(b, item.ITunesImage.Href)
+		for j := range item.Enclosures {
+			item.Enclosures[j].URL = resolveReference(b, item.Enclosures[j].URL)
+		}
+		for j := range item.MediaContent {
+			item.MediaContent[j].URL = resolveReference(b, item.MediaContent[j].URL)
+		}
+		for j := range item.MediaThumbnails {
+			item.MediaThumbnails[j].URL = resolveReference(b, item.MediaThumbnails[j].URL)
+		}
+		for j := range item.MediaGroups {
+			group := &item.MediaGroups[j]
+			for k := range group.Content {
+				group.Content[k].URL = resolveReference(b, group.Content[k].URL)
+			}
+			for k := range group.Thumbnails {
+				group.Thumbnails[k].URL = resolveReference(b, group.Thumbnails[k].URL)
+			}
+		}
+
+		item.Description = resolveHTMLURLs(item.Description, b)
+		item.Content = resolveHTMLURLs(item.Content, b)
+	}
+}
+
+// resolveBase applies an xml:base value on top of the inherited base
+func resolveBase(base *url.URL, xmlBase string) *url.URL {
+	xmlBase = strings.TrimSpace(xmlBase)
+	if xmlBase == "" {
+		return base
+	}
+	ref, err := url.Parse(xmlBase)
+	if err != nil {
+		return base
+	}
+	return base.ResolveReference(ref)
+}
+
+func resolveReference(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(parsed).String()
+}
+
+// resolveHTMLURLs rewrites relative href/src/cite attributes in an HTML fragment
+func resolveHTMLURLs(fragment string, base *url.URL) string {
+	if !strings.Contains(fragment, "href") && !strings.Contains(fragment, "src") && !strings.Contains(fragment, "cite") {
+		return fragment
+	}
+
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), bodyContext)
+	if err != nil {
+		return fragment
+	}
+
+	changed := false
+	for _, node := range nodes {
+		if resolveNodeURLs(node, base) {
+			changed = true
+		}
+	}
+	// Rendering rewrites the markup (quotes, entities), keep the original
+	// unless a URL actually changed
+	if !changed {
+		return fragment
+	}
+
+	var buf strings.Builder
+	for _, node := range nodes {
+		if err := html.Render(&buf, node); err != nil {
+			return fragment
+		}
+	}
+	return buf.String()
+}
+
+// resolveNodeURLs reports whether any attribute changed
+func resolveNodeURLs(node *html.Node, base *url.URL) bool {
+	changed := false
+	if node.Type == html.ElementNode {
+		for i, attr := range node.Attr {
+			if attr.Namespace == "" && urlAttributes[attr.Key] {
+				resolved := resolveReference(base, attr.Val)
+				if resolved != attr.Val {
+					node.Attr[i].Val = resolved
+					changed = true
+				}
+			}
+		}
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if resolveNodeURLs(child, base) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// trackingParams are query parameters that identify the visitor, not the page
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true, "yclid": true,
+	"mc_cid": true, "mc_eid": true, "igshid": true, "_hsenc": true, "_hsmi": true,
+	"ref": true, "ref_src": true, "cmpid": true, "spm": true,
+}
+
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasPrefix(key, "utm_") || trackingParams[key]
+}
+
+// normalizeURL is the form URLs are stored and fetched in: lower-case scheme
+// and host, no fragment. Anything else may matter to the server, so it stays.
+func normalizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return raw
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	return parsed.String()
+}
+
+// urlKey identifies the resource behind a feed or post URL for comparisons.
+// It ignores the scheme, a leading www., default ports, trailing slashes,
+// tracking parameters and the order of query parameters, so http/https and
+// www/non-www copies of a feed match, and so does an article shared with
+// utm_source=...
+func urlKey(raw string) string {
+	parsed, err := url.Parse(normalizeURL(raw))
+	if err != nil || parsed.Host == "" {
+		return strings.TrimSpace(raw)
+	}
+
+	host := parsed.Hostname()
+	if port := parsed.Port(); port != "" && !(port == "80" && parsed.Scheme == "http") && !(port == "443" && parsed.Scheme == "https") {
+		host += ":" + port
+	}
+	key := strings.TrimPrefix(host, "www.") + strings.TrimRight(parsed.EscapedPath(), "/")
+
+	if parsed.RawQuery != "" {
+		query := parsed.Query()
+		for param := range query {
+			if isTrackingParam(param) {
+				query.Del(param)
+			}
+		}
+		if encoded := query.Encode(); encoded != "" {
+			key += "?" + encoded
+		}
+	}
+	return key
+}
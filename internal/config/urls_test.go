@@ -0,0 +1,141 @@
+package config
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://example.com/feed", "https://example.com/feed"},
+		{"  https://example.com/feed  ", "https://example.com/feed"},
+		{"HTTPS://WWW.Example.COM/Feed", "https://www.example.com/Feed"},
+		{"https://example.com/feed#latest", "https://example.com/feed"},
+		// Everything the server may care about stays
+		{"https://www.example.com:443/feed/", "https://www.example.com:443/feed/"},
+		{"https://example.com/feed?b=2&a=1", "https://example.com/feed?b=2&a=1"},
+		{"https://example.com/feed?utm_source=x&id=1", "https://example.com/feed?utm_source=x&id=1"},
+		{"http://example.com/a%2Fb", "http://example.com/a%2Fb"},
+		// Not absolute, left alone
+		{"/relative/path", "/relative/path"},
+		{"example.com/feed", "example.com/feed"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeURL(tt.in); got != tt.want {
+			t.Errorf("normalizeURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestURLKey(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://example.com/feed", "example.com/feed"},
+		{"http://www.example.com/feed/", "example.com/feed"},
+		{"HTTPS://WWW.Example.com:443/feed/#x", "example.com/feed"},
+		{"http://example.com:80/feed", "example.com/feed"},
+		{"http://example.com:8080/feed", "example.com:8080/feed"},
+		{"https://example.com:80/feed", "example.com:80/feed"},
+		{"https://example.com/", "example.com"},
+		{"https://example.com/feed?b=2&a=1", "example.com/feed?a=1&b=2"},
+		// Tracking parameters are dropped, from feed and post URLs alike
+		{"https://example.com/feed.xml?utm_source=newsletter", "example.com/feed.xml"},
+		{"https://example.com/post?utm_source=rss&utm_medium=feed", "example.com/post"},
+		{"https://example.com/post?id=7&fbclid=abc&ref=home", "example.com/post?id=7"},
+		{"https://example.com/post?UTM_Campaign=x", "example.com/post"},
+		// The path keeps its case
+		{"https://example.com/Post", "example.com/Post"},
+		// Relative links cannot be keyed, they are kept as they are
+		{"/post/1", "/post/1"},
+		{" post/1 ", "post/1"},
+	}
+
+	for _, tt := range tests {
+		if got := urlKey(tt.in); got != tt.want {
+			t.Errorf("urlKey(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestResolveHTMLURLs(t *testing.T) {
+	base, err := url.Parse("https://example.com/blog/post/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "no URLs",
+			in:   `<p>Plain "quoted" text</p>`,
+			want: `<p>Plain "quoted" text</p>`,
+		},
+		{
+			name: "absolute URLs keep the original markup",
+			in:   `<p>excited <a href=https://x.com/>x</a> &amp; <img src='https://x.com/a.png'></p>`,
+			want: `<p>excited <a href=https://x.com/>x</a> &amp; <img src='https://x.com/a.png'></p>`,
+		},
+		{
+			name: "relative href",
+			in:   `<a href="image-1">x</a>`,
+			want: `<a href="https://example.com/blog/post/image-1">x</a>`,
+		},
+		{
+			name: "root-relative src",
+			in:   `<img src="/media/a.png">`,
+			want: `<img src="https://example.com/media/a.png"/>`,
+		},
+		{
+			name: "parent-relative cite",
+			in:   `<blockquote cite="../other">q</blockquote>`,
+			want: `<blockquote cite="https://example.com/blog/other">q</blockquote>`,
+		},
+		{
+			name: "protocol-relative",
+			in:   `<img src="//cdn.example.net/a.png">`,
+			want: `<img src="https://cdn.example.net/a.png"/>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveHTMLURLs(tt.in, base); got != tt.want {
+				t.Errorf("resolveHTMLURLs(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveFeedURLs(t *testing.T) {
+	var feed RSSFeed
+	feed.Channel.Link = "/"
+	feed.Channel.Item = []RSSItem{
+		{Link: "/posts/1", Description: `<img src="a.png">`},
+		{Base: "https://cdn.example.org/x/", Link: "2"},
+	}
+
+	resolveFeedURLs(&feed, "https://example.com/feed.xml")
+
+	if feed.Channel.Link != "https://example.com/" {
+		t.Errorf("channel link = %q", feed.Channel.Link)
+	}
+	first := feed.Channel.Item[0]
+	if first.Link != "https://example.com/posts/1" || first.RawLink != "/posts/1" {
+		t.Errorf("item link = %q, raw %q", first.Link, first.RawLink)
+	}
+	if first.Description != `<img src="https://example.com/a.png"/>` {
+		t.Errorf("item description = %q", first.Description)
+	}
+	if got := feed.Channel.Item[1].Link; got != "https://cdn.example.org/x/2" {
+		t.Errorf("item with xml:base link = %q", got)
+	}
+}
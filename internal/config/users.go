@@ -0,0 +1,176 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/BabichevDima/aggregator/internal/database"
+)
+
+// userToModify looks up an account the user may rename or delete: their own,
+// or any account for an admin
+func userToModify(s *State, user database.User, name string) (database.User, error) {
+	target, err := getUser(s.DB, name)
+	if err != nil {
+		return database.User{}, err
+	}
+	if target.ID != user.ID && !user.IsAdmin {
+		return database.User{}, fmt.Errorf("only '%s' or an admin can change this account", target.Name)
+	}
+	return *target, nil
+}
+
+// countUnread counts the unread posts browse would show, so not those the
+// user's hide rules match
+func countUnread(s *State, user database.User) (int, error) {
+	hideRules, err := getHideRules(s, user)
+	if err != nil {
+		return 0, err
+	}
+	posts, err := s.DB.GetUnreadPostsForUser(context.Background(), user.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get unread posts: %w", err)
+	}
+
+	matcher := newRuleMatcher()
+	unread := 0
+	for _, post := range posts {
+		subject := ruleSubject{
+			Title:       post.Title,
+			Description: htmlText(post.Description.String + " " + post.Content.String),
+			Author:      post.Author.String,
+			Feed:        post.FeedName,
+		}
+		if post.Categories != "" {
+			subject.Categories = strings.Split(post.Categories, ", ")
+		}
+		if !matcher.hides(hideRules, subject) {
+			unread++
+		}
+	}
+	return unread, nil
+}
+
+func HandlerWhoami(s *State, cmd Command, user database.User) error {
+	if err := validateArgs(cmd.Args, 0, "whoami"); err != nil {
+		return err
+	}
+
+	stats, err := s.DB.GetUserStats(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	unread, err := countUnread(s, user)
+	if err != nil {
+		return err
+	}
+
+	name := user.Name
+	if user.IsAdmin {
+		name += " (admin)"
+	}
+	fmt.Printf("User: %s\n", name)
+	fmt.Printf("Registered: %s\n", user.CreatedAt.Format("2006-01-02 15:04"))
+	fmt.Printf("Following: %d feeds\n", stats.Subscriptions)
+	fmt.Printf("Feeds added: %d\n", stats.FeedsAdded)
+	fmt.Printf("Unread posts: %d\n", unread)
+	return nil
+}
+
+// HandlerRenameUser renames an account: renameuser <name> <new-name>
+func HandlerRenameUser(s *State, cmd Command, user database.User) error {
+	if err := validateArgs(cmd.Args, 2, "renameuser"); err != nil {
+		return err
+	}
+
+	target, err := userToModify(s, user, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+	newName := strings.TrimSpace(cmd.Args[1])
+	if newName == "" {
+		return fmt.Errorf("username cannot be empty")
+	}
+
+	renamed, err := s.DB.RenameUser(context.Background(), database.RenameUserParams{
+		ID:   target.ID,
+		Name: newName,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return fmt.Errorf("user '%s' already exists", newName)
+		}
+		return fmt.Errorf("failed to rename user: %w", err)
+	}
+
+	// Stay logged in under the new name
+	if s.Config.CurrentUserName == target.Name {
+		if err := updateConfigUser(s.Config, renamed.Name); err != nil {
+			return fmt.Errorf("failed to update config: %w", err)
+		}
+	}
+
+	fmt.Printf("User '%s' renamed to '%s'\n", target.Name, renamed.Name)
+	return nil
+}
+
+// HandlerDeleteUser deletes an account with its follows, folders and rules:
+// deleteuser <name>. Feeds the user added pass to their other followers, or
+// to the admin, so only reap deletes them.
+func HandlerDeleteUser(s *State, cmd Command, user database.User) error {
+	if err := validateArgs(cmd.Args, 1, "deleteuser"); err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	target, err := userToModify(s, user, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+	qtx := s.DB.WithTx(tx)
+
+	transferred, err := qtx.TransferFollowedFeeds(ctx, target.ID)
+	if err != nil {
+		return fmt.Errorf("failed to transfer feeds: %w", err)
+	}
+	if err := qtx.EnsureAdmin(ctx, target.ID); err != nil {
+		return fmt.Errorf("failed to pick a new admin: %w", err)
+	}
+	// The rest go to the admin, reap deletes them after the grace period
+	toAdmin, err := qtx.TransferFeedsToAdmin(ctx, target.ID)
+	if err != nil {
+		return fmt.Errorf("failed to transfer feeds: %w", err)
+	}
+	if err := qtx.DeleteUser(ctx, target.ID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if err := qtx.MarkOrphanedFeeds(ctx); err != nil {
+		return fmt.Errorf("failed to update orphaned feeds: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	fmt.Printf("User '%s' deleted\n", target.Name)
+	if transferred > 0 {
+		fmt.Printf("%d feeds they added passed to their other followers\n", transferred)
+	}
+	if toAdmin > 0 {
+		fmt.Printf("%d feeds nobody else follows passed to the admin, reap removes them in time\n", toAdmin)
+	}
+
+	if s.Config.CurrentUserName == target.Name {
+		if err := updateConfigUser(s.Config, ""); err != nil {
+			return fmt.Errorf("failed to update config: %w", err)
+		}
+		fmt.Println("You are logged out, use login or register")
+	}
+	return nil
+}
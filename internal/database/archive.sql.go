@@ -0,0 +1,268 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: archive.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const getPostsToArchive = `-- name: GetPostsToArchive :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid, posts.content_hash, posts.content, posts.author, posts.comments_url, posts.article_content, posts.article_fetched_at, posts.snapshot, posts.archived_at, posts.url_key FROM posts
+WHERE posts.snapshot IS NULL
+AND (NOT $2::boolean OR EXISTS (
+    SELECT 1 FROM post_stars WHERE post_stars.post_id = posts.id
+))
+ORDER BY posts.created_at DESC
+LIMIT $1
+`
+
+type GetPostsToArchiveParams struct {
+	Limit       int32
+	StarredOnly bool
+}
+
+func (q *Queries) GetPostsToArchive(ctx context.Context, arg GetPostsToArchiveParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsToArchive, arg.Limit, arg.StarredOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Guid,
+			&i.ContentHash,
+			&i.Content,
+			&i.Author,
+			&i.CommentsUrl,
+			&i.ArticleContent,
+			&i.ArticleFetchedAt,
+			&i.Snapshot,
+			&i.ArchivedAt,
+			&i.UrlKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStarredPostsForUser = `-- name: GetStarredPostsForUser :many
+SELECT
+    posts.id,
+    posts.created_at,
+    posts.updated_at,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    posts.feed_id,
+    posts.content,
+    posts.author,
+    posts.comments_url,
+    COALESCE((
+        SELECT string_agg(post_categories.name, ', ' ORDER BY post_categories.name)
+        FROM post_categories
+        WHERE post_categories.post_id = posts.id
+    ), '')::text AS categories,
+    COALESCE((
+        SELECT string_agg(post_tags.tag, ', ' ORDER BY post_tags.tag)
+        FROM post_tags
+        WHERE post_tags.post_id = posts.id AND post_tags.user_id = post_stars.user_id
+    ), '')::text AS tags,
+    COALESCE(feed_follows.title_override, feeds.name)::text AS feed_name,
+    (post_reads.read_at IS NOT NULL)::boolean AS is_read,
+    TRUE::boolean AS is_starred,
+    posts.snapshot
+FROM post_stars
+INNER JOIN posts ON post_stars.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN feed_follows ON feed_follows.feed_id = feeds.id AND feed_follows.user_id = post_stars.user_id
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = post_stars.user_id
+WHERE post_stars.user_id = $1
+AND ($3::uuid IS NULL OR feed_follows.folder_id = $3::uuid)
+AND ($4::text IS NULL OR posts.author ILIKE '%' || $4::text || '%')
+AND ($5::text IS NULL OR EXISTS (
+    SELECT 1 FROM post_categories
+    WHERE post_categories.post_id = posts.id
+    AND lower(post_categories.name) = lower($5::text)
+))
+AND ($6::text IS NULL OR EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.post_id = posts.id
+    AND post_tags.user_id = post_stars.user_id
+    AND post_tags.tag = lower($6::text)
+))
+AND ($7::text IS NULL OR (
+    posts.title ILIKE '%' || $7::text || '%'
+    OR posts.description ILIKE '%' || $7::text || '%'
+    OR posts.content ILIKE '%' || $7::text || '%'
+    OR posts.author ILIKE '%' || $7::text || '%'
+    OR EXISTS (
+        SELECT 1 FROM post_categories
+        WHERE post_categories.post_id = posts.id
+        AND post_categories.name ILIKE '%' || $7::text || '%'
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_tags
+        WHERE post_tags.post_id = posts.id
+        AND post_tags.user_id = post_stars.user_id
+        AND post_tags.tag ILIKE '%' || $7::text || '%'
+    )
+))
+ORDER BY COALESCE(posts.published_at, posts.created_at)
+    + make_interval(hours => LEAST(GREATEST(COALESCE(feed_follows.priority, 0), -24), 24)) DESC,
+    COALESCE(feed_follows.priority, 0) DESC
+LIMIT $2
+`
+
+type GetStarredPostsForUserParams struct {
+	UserID   uuid.UUID
+	Limit    int32
+	FolderID uuid.NullUUID
+	Author   sql.NullString
+	Category sql.NullString
+	Tag      sql.NullString
+	Query    sql.NullString
+}
+
+type GetStarredPostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	Author      sql.NullString
+	CommentsUrl sql.NullString
+	Categories  string
+	Tags        string
+	FeedName    string
+	IsRead      bool
+	IsStarred   bool
+	Snapshot    sql.NullString
+}
+
+// Same columns as GetPostsForUser; starred posts stay listed after an unfollow
+// Newest first; each point of priority moves a feed's posts up by an hour, at most a day
+func (q *Queries) GetStarredPostsForUser(ctx context.Context, arg GetStarredPostsForUserParams) ([]GetStarredPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getStarredPostsForUser,
+		arg.UserID,
+		arg.Limit,
+		arg.FolderID,
+		arg.Author,
+		arg.Category,
+		arg.Tag,
+		arg.Query,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetStarredPostsForUserRow
+	for rows.Next() {
+		var i GetStarredPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.Author,
+			&i.CommentsUrl,
+			&i.Categories,
+			&i.Tags,
+			&i.FeedName,
+			&i.IsRead,
+			&i.IsStarred,
+			&i.Snapshot,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPostSnapshot = `-- name: SetPostSnapshot :exec
+UPDATE posts
+SET snapshot = $2, archived_at = NOW()
+WHERE id = $1
+`
+
+type SetPostSnapshotParams struct {
+	ID       uuid.UUID
+	Snapshot sql.NullString
+}
+
+func (q *Queries) SetPostSnapshot(ctx context.Context, arg SetPostSnapshotParams) error {
+	_, err := q.db.ExecContext(ctx, setPostSnapshot, arg.ID, arg.Snapshot)
+	return err
+}
+
+const starPost = `-- name: StarPost :exec
+INSERT INTO post_stars (user_id, post_id, starred_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type StarPostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) StarPost(ctx context.Context, arg StarPostParams) error {
+	_, err := q.db.ExecContext(ctx, starPost, arg.UserID, arg.PostID)
+	return err
+}
+
+const unstarPost = `-- name: UnstarPost :exec
+DELETE FROM post_stars
+WHERE user_id = $1 AND post_id = $2
+`
+
+type UnstarPostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) UnstarPost(ctx context.Context, arg UnstarPostParams) error {
+	_, err := q.db.ExecContext(ctx, unstarPost, arg.UserID, arg.PostID)
+	return err
+}
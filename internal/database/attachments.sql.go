@@ -0,0 +1,124 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: attachments.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createAttachment = `-- name: CreateAttachment :exec
+INSERT INTO attachments (id, created_at, post_id, kind, url, mime_type, length, duration_seconds, episode)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+ON CONFLICT (post_id, url) DO NOTHING
+`
+
+type CreateAttachmentParams struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	PostID          uuid.UUID
+	Kind            string
+	Url             string
+	MimeType        sql.NullString
+	Length          sql.NullInt64
+	DurationSeconds sql.NullInt32
+	Episode         sql.NullInt32
+}
+
+func (q *Queries) CreateAttachment(ctx context.Context, arg CreateAttachmentParams) error {
+	_, err := q.db.ExecContext(ctx, createAttachment,
+		arg.ID,
+		arg.CreatedAt,
+		arg.PostID,
+		arg.Kind,
+		arg.Url,
+		arg.MimeType,
+		arg.Length,
+		arg.DurationSeconds,
+		arg.Episode,
+	)
+	return err
+}
+
+const deletePostAttachments = `-- name: DeletePostAttachments :exec
+DELETE FROM attachments WHERE post_id = $1
+`
+
+func (q *Queries) DeletePostAttachments(ctx context.Context, postID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deletePostAttachments, postID)
+	return err
+}
+
+const getAttachmentsForPost = `-- name: GetAttachmentsForPost :many
+SELECT
+    attachments.id, attachments.created_at, attachments.post_id, attachments.kind, attachments.url, attachments.mime_type, attachments.length, attachments.duration_seconds, attachments.episode,
+    feeds.name AS feed_name
+FROM attachments
+INNER JOIN posts ON attachments.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE attachments.post_id = $1
+ORDER BY attachments.created_at ASC, attachments.url ASC
+`
+
+type GetAttachmentsForPostRow struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	PostID          uuid.UUID
+	Kind            string
+	Url             string
+	MimeType        sql.NullString
+	Length          sql.NullInt64
+	DurationSeconds sql.NullInt32
+	Episode         sql.NullInt32
+	FeedName        string
+}
+
+func (q *Queries) GetAttachmentsForPost(ctx context.Context, postID uuid.UUID) ([]GetAttachmentsForPostRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAttachmentsForPost, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAttachmentsForPostRow
+	for rows.Next() {
+		var i GetAttachmentsForPostRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.PostID,
+			&i.Kind,
+			&i.Url,
+			&i.MimeType,
+			&i.Length,
+			&i.DurationSeconds,
+			&i.Episode,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,175 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: folders.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createFolder = `-- name: CreateFolder :one
+INSERT INTO folders (id, created_at, updated_at, user_id, name)
+VALUES ($1, NOW(), NOW(), $2, $3)
+RETURNING id, created_at, updated_at, user_id, name
+`
+
+type CreateFolderParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) CreateFolder(ctx context.Context, arg CreateFolderParams) (Folder, error) {
+	row := q.db.QueryRowContext(ctx, createFolder, arg.ID, arg.UserID, arg.Name)
+	var i Folder
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+	)
+	return i, err
+}
+
+const deleteFolder = `-- name: DeleteFolder :exec
+DELETE FROM folders WHERE id = $1
+`
+
+func (q *Queries) DeleteFolder(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFolder, id)
+	return err
+}
+
+const getFolderByName = `-- name: GetFolderByName :one
+SELECT id, created_at, updated_at, user_id, name FROM folders
+WHERE user_id = $1 AND name = $2
+`
+
+type GetFolderByNameParams struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) GetFolderByName(ctx context.Context, arg GetFolderByNameParams) (Folder, error) {
+	row := q.db.QueryRowContext(ctx, getFolderByName, arg.UserID, arg.Name)
+	var i Folder
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+	)
+	return i, err
+}
+
+const getFoldersForUser = `-- name: GetFoldersForUser :many
+SELECT
+    folders.id, folders.created_at, folders.updated_at, folders.user_id, folders.name,
+    COUNT(feed_follows.id) AS feed_count
+FROM folders
+LEFT JOIN feed_follows ON feed_follows.folder_id = folders.id
+WHERE folders.user_id = $1
+GROUP BY folders.id
+ORDER BY folders.name
+`
+
+type GetFoldersForUserRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	FeedCount int64
+}
+
+func (q *Queries) GetFoldersForUser(ctx context.Context, userID uuid.UUID) ([]GetFoldersForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFoldersForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFoldersForUserRow
+	for rows.Next() {
+		var i GetFoldersForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+			&i.FeedCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markAllPostsRead = `-- name: MarkAllPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, NOW()
+FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND ($2::uuid IS NULL OR feed_follows.folder_id = $2::uuid)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MarkAllPostsReadParams struct {
+	UserID   uuid.UUID
+	FolderID uuid.NullUUID
+}
+
+func (q *Queries) MarkAllPostsRead(ctx context.Context, arg MarkAllPostsReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markAllPostsRead, arg.UserID, arg.FolderID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFollowFolder = `-- name: SetFollowFolder :execrows
+UPDATE feed_follows
+SET folder_id = $1, updated_at = NOW()
+WHERE feed_follows.user_id = $2
+AND feed_follows.feed_id IN (
+    SELECT id FROM feeds
+    WHERE url_key = $3
+    OR (url_key IS NULL AND url = $4)
+)
+`
+
+type SetFollowFolderParams struct {
+	FolderID uuid.NullUUID
+	UserID   uuid.UUID
+	UrlKey   sql.NullString
+	Url      string
+}
+
+func (q *Queries) SetFollowFolder(ctx context.Context, arg SetFollowFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFollowFolder,
+		arg.FolderID,
+		arg.UserID,
+		arg.UrlKey,
+		arg.Url,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
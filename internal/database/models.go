@@ -11,33 +11,144 @@ import (
 	"github.com/google/uuid"
 )
 
+type Attachment struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	PostID          uuid.UUID
+	Kind            string
+	Url             string
+	MimeType        sql.NullString
+	Length          sql.NullInt64
+	DurationSeconds sql.NullInt32
+	Episode         sql.NullInt32
+}
+
 type Feed struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Name            string
+	Url             string
+	UserID          uuid.UUID
+	LastFetchedAt   sql.NullTime
+	NextFetchAt     sql.NullTime
+	LastFetchError  sql.NullString
+	DeactivatedAt   sql.NullTime
+	Title           sql.NullString
+	Description     sql.NullString
+	SiteUrl         sql.NullString
+	Language        sql.NullString
+	ImageUrl        sql.NullString
+	ExtractFullText bool
+	RetentionDays   sql.NullInt32
+	RetentionItems  sql.NullInt32
+	UrlKey          sql.NullString
+	OrphanedAt      sql.NullTime
+}
+
+type FeedFollow struct {
 	ID            uuid.UUID
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
-	Name          string
-	Url           string
 	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	FeedID        uuid.UUID
+	FolderID      uuid.NullUUID
+	TitleOverride sql.NullString
+	Priority      int32
+	Muted         bool
+	Notify        bool
 }
 
-type FeedFollow struct {
+type Folder struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	UserID    uuid.UUID
-	FeedID    uuid.UUID
+	Name      string
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Message   string
+	ReadAt    sql.NullTime
+	PostID    uuid.NullUUID
 }
 
 type Post struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Title            string
+	Url              string
+	Description      sql.NullString
+	PublishedAt      sql.NullTime
+	FeedID           uuid.UUID
+	Guid             string
+	ContentHash      string
+	Content          sql.NullString
+	Author           sql.NullString
+	CommentsUrl      sql.NullString
+	ArticleContent   sql.NullString
+	ArticleFetchedAt sql.NullTime
+	Snapshot         sql.NullString
+	ArchivedAt       sql.NullTime
+	UrlKey           sql.NullString
+}
+
+type PostCategory struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type PostRevision struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	PostID      uuid.UUID
 	Title       string
 	Url         string
 	Description sql.NullString
 	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ContentHash string
+	Content     sql.NullString
+}
+
+type PostStar struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	StarredAt time.Time
+}
+
+type PostTag struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type PrunedPost struct {
+	FeedID   uuid.UUID
+	Guid     string
+	PrunedAt time.Time
+}
+
+type Rule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Action    string
+	ActionArg sql.NullString
 }
 
 type User struct {
@@ -45,4 +156,5 @@ type User struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Name      string
+	IsAdmin   bool
 }
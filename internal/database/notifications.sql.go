@@ -0,0 +1,135 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: notifications.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createNotification = `-- name: CreateNotification :exec
+INSERT INTO notifications (id, created_at, user_id, feed_id, post_id, message)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4)
+`
+
+type CreateNotificationParams struct {
+	UserID  uuid.UUID
+	FeedID  uuid.NullUUID
+	PostID  uuid.NullUUID
+	Message string
+}
+
+func (q *Queries) CreateNotification(ctx context.Context, arg CreateNotificationParams) error {
+	_, err := q.db.ExecContext(ctx, createNotification,
+		arg.UserID,
+		arg.FeedID,
+		arg.PostID,
+		arg.Message,
+	)
+	return err
+}
+
+const getUnreadNotifications = `-- name: GetUnreadNotifications :many
+SELECT id, created_at, user_id, feed_id, message, read_at, post_id FROM notifications
+WHERE user_id = $1 AND read_at IS NULL
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetUnreadNotifications(ctx context.Context, userID uuid.UUID) ([]Notification, error) {
+	rows, err := q.db.QueryContext(ctx, getUnreadNotifications, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Notification
+	for rows.Next() {
+		var i Notification
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.Message,
+			&i.ReadAt,
+			&i.PostID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markNotificationsRead = `-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL
+`
+
+func (q *Queries) MarkNotificationsRead(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markNotificationsRead, userID)
+	return err
+}
+
+const notifyFeedFollowers = `-- name: NotifyFeedFollowers :exec
+INSERT INTO notifications (id, created_at, user_id, feed_id, message)
+SELECT gen_random_uuid(), NOW(), feed_follows.user_id, feed_follows.feed_id, $2
+FROM feed_follows
+WHERE feed_follows.feed_id = $1
+`
+
+type NotifyFeedFollowersParams struct {
+	FeedID  uuid.UUID
+	Message string
+}
+
+func (q *Queries) NotifyFeedFollowers(ctx context.Context, arg NotifyFeedFollowersParams) error {
+	_, err := q.db.ExecContext(ctx, notifyFeedFollowers, arg.FeedID, arg.Message)
+	return err
+}
+
+const notifyNewPost = `-- name: NotifyNewPost :exec
+INSERT INTO notifications (id, created_at, user_id, feed_id, post_id, message)
+SELECT
+    gen_random_uuid(),
+    NOW(),
+    feed_follows.user_id,
+    feed_follows.feed_id,
+    $1,
+    'New post in ' || COALESCE(feed_follows.title_override, feeds.name) || ': ' || $2::text
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.feed_id = $3
+AND feed_follows.notify
+AND NOT feed_follows.muted
+AND NOT feed_follows.user_id = ANY($4::uuid[])
+`
+
+type NotifyNewPostParams struct {
+	PostID    uuid.NullUUID
+	Title     string
+	FeedID    uuid.UUID
+	HiddenFor []uuid.UUID
+}
+
+// Followers whose hide rules match the post
+func (q *Queries) NotifyNewPost(ctx context.Context, arg NotifyNewPostParams) error {
+	_, err := q.db.ExecContext(ctx, notifyNewPost,
+		arg.PostID,
+		arg.Title,
+		arg.FeedID,
+		pq.Array(arg.HiddenFor),
+	)
+	return err
+}
@@ -0,0 +1,184 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: orphans.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deleteUnsavedPosts = `-- name: DeleteUnsavedPosts :execrows
+DELETE FROM posts
+WHERE feed_id = $1
+AND NOT EXISTS (SELECT 1 FROM post_stars WHERE post_stars.post_id = posts.id)
+AND NOT EXISTS (SELECT 1 FROM post_tags WHERE post_tags.post_id = posts.id)
+`
+
+// Deletes the posts of a feed nobody starred or tagged
+func (q *Queries) DeleteUnsavedPosts(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUnsavedPosts, feedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getOrphanedFeeds = `-- name: GetOrphanedFeeds :many
+SELECT
+    feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.next_fetch_at, feeds.last_fetch_error, feeds.deactivated_at, feeds.title, feeds.description, feeds.site_url, feeds.language, feeds.image_url, feeds.extract_full_text, feeds.retention_days, feeds.retention_items, feeds.url_key, feeds.orphaned_at,
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = feeds.id) AS post_count,
+    (
+        SELECT COUNT(*) FROM posts
+        WHERE posts.feed_id = feeds.id
+        AND (
+            EXISTS (SELECT 1 FROM post_stars WHERE post_stars.post_id = posts.id)
+            OR EXISTS (SELECT 1 FROM post_tags WHERE post_tags.post_id = posts.id)
+        )
+    ) AS saved_count
+FROM feeds
+WHERE feeds.orphaned_at IS NOT NULL
+ORDER BY feeds.orphaned_at, feeds.name
+`
+
+type GetOrphanedFeedsRow struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Name            string
+	Url             string
+	UserID          uuid.UUID
+	LastFetchedAt   sql.NullTime
+	NextFetchAt     sql.NullTime
+	LastFetchError  sql.NullString
+	DeactivatedAt   sql.NullTime
+	Title           sql.NullString
+	Description     sql.NullString
+	SiteUrl         sql.NullString
+	Language        sql.NullString
+	ImageUrl        sql.NullString
+	ExtractFullText bool
+	RetentionDays   sql.NullInt32
+	RetentionItems  sql.NullInt32
+	UrlKey          sql.NullString
+	OrphanedAt      sql.NullTime
+	PostCount       int64
+	SavedCount      int64
+}
+
+func (q *Queries) GetOrphanedFeeds(ctx context.Context) ([]GetOrphanedFeedsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getOrphanedFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOrphanedFeedsRow
+	for rows.Next() {
+		var i GetOrphanedFeedsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.NextFetchAt,
+			&i.LastFetchError,
+			&i.DeactivatedAt,
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.ExtractFullText,
+			&i.RetentionDays,
+			&i.RetentionItems,
+			&i.UrlKey,
+			&i.OrphanedAt,
+			&i.PostCount,
+			&i.SavedCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForFeed = `-- name: GetPostsForFeed :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content_hash, content, author, comments_url, article_content, article_fetched_at, snapshot, archived_at, url_key FROM posts
+WHERE feed_id = $1
+ORDER BY COALESCE(published_at, created_at), id
+`
+
+func (q *Queries) GetPostsForFeed(ctx context.Context, feedID uuid.UUID) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Guid,
+			&i.ContentHash,
+			&i.Content,
+			&i.Author,
+			&i.CommentsUrl,
+			&i.ArticleContent,
+			&i.ArticleFetchedAt,
+			&i.Snapshot,
+			&i.ArchivedAt,
+			&i.UrlKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markOrphanedFeeds = `-- name: MarkOrphanedFeeds :exec
+UPDATE feeds
+SET orphaned_at = CASE
+    WHEN EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id) THEN NULL
+    ELSE NOW()
+END
+WHERE (feeds.orphaned_at IS NULL) <> EXISTS (
+    SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id
+)
+`
+
+// Start the grace period of feeds that lost their last follower, and stop it
+// for feeds that were followed again
+func (q *Queries) MarkOrphanedFeeds(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, markOrphanedFeeds)
+	return err
+}
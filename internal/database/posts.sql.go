@@ -0,0 +1,528 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: posts.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const addPostCategory = `-- name: AddPostCategory :exec
+INSERT INTO post_categories (post_id, name)
+VALUES ($1, $2)
+ON CONFLICT (post_id, name) DO NOTHING
+`
+
+type AddPostCategoryParams struct {
+	PostID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) AddPostCategory(ctx context.Context, arg AddPostCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, addPostCategory, arg.PostID, arg.Name)
+	return err
+}
+
+const createPostRevision = `-- name: CreatePostRevision :exec
+INSERT INTO post_revisions (id, created_at, post_id, title, url, description, published_at, content_hash, content)
+SELECT $1, NOW(), posts.id, posts.title, posts.url, posts.description, posts.published_at, posts.content_hash, posts.content
+FROM posts
+WHERE posts.id = $2
+`
+
+type CreatePostRevisionParams struct {
+	ID     uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) CreatePostRevision(ctx context.Context, arg CreatePostRevisionParams) error {
+	_, err := q.db.ExecContext(ctx, createPostRevision, arg.ID, arg.PostID)
+	return err
+}
+
+const deletePost = `-- name: DeletePost :exec
+DELETE FROM posts WHERE id = $1
+`
+
+func (q *Queries) DeletePost(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deletePost, id)
+	return err
+}
+
+const deletePostCategories = `-- name: DeletePostCategories :exec
+DELETE FROM post_categories WHERE post_id = $1
+`
+
+func (q *Queries) DeletePostCategories(ctx context.Context, postID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deletePostCategories, postID)
+	return err
+}
+
+const getDuplicateCandidates = `-- name: GetDuplicateCandidates :many
+SELECT posts.id, posts.feed_id, posts.guid, posts.url_key::text AS url_key
+FROM posts
+WHERE posts.url_key IS NOT NULL AND posts.url_key <> ''
+AND EXISTS (
+    SELECT 1 FROM posts other
+    WHERE other.feed_id = posts.feed_id
+    AND other.url_key = posts.url_key
+    AND other.id <> posts.id
+)
+ORDER BY posts.feed_id, posts.url_key, posts.created_at, posts.id
+`
+
+type GetDuplicateCandidatesRow struct {
+	ID     uuid.UUID
+	FeedID uuid.UUID
+	Guid   string
+	UrlKey string
+}
+
+// Posts of one feed sharing a url_key with another, oldest first. Whether
+// they are the same item is up to their GUIDs.
+func (q *Queries) GetDuplicateCandidates(ctx context.Context) ([]GetDuplicateCandidatesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDuplicateCandidates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDuplicateCandidatesRow
+	for rows.Next() {
+		var i GetDuplicateCandidatesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FeedID,
+			&i.Guid,
+			&i.UrlKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostByGUID = `-- name: GetPostByGUID :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content_hash, content, author, comments_url, article_content, article_fetched_at, snapshot, archived_at, url_key FROM posts
+WHERE feed_id = $1 AND guid = $2
+`
+
+type GetPostByGUIDParams struct {
+	FeedID uuid.UUID
+	Guid   string
+}
+
+func (q *Queries) GetPostByGUID(ctx context.Context, arg GetPostByGUIDParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByGUID, arg.FeedID, arg.Guid)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+		&i.ContentHash,
+		&i.Content,
+		&i.Author,
+		&i.CommentsUrl,
+		&i.ArticleContent,
+		&i.ArticleFetchedAt,
+		&i.Snapshot,
+		&i.ArchivedAt,
+		&i.UrlKey,
+	)
+	return i, err
+}
+
+const getPostByID = `-- name: GetPostByID :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content_hash, content, author, comments_url, article_content, article_fetched_at, snapshot, archived_at, url_key FROM posts WHERE id = $1
+`
+
+func (q *Queries) GetPostByID(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByID, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+		&i.ContentHash,
+		&i.Content,
+		&i.Author,
+		&i.CommentsUrl,
+		&i.ArticleContent,
+		&i.ArticleFetchedAt,
+		&i.Snapshot,
+		&i.ArchivedAt,
+		&i.UrlKey,
+	)
+	return i, err
+}
+
+const getPostCategories = `-- name: GetPostCategories :many
+SELECT name FROM post_categories
+WHERE post_id = $1
+ORDER BY name
+`
+
+func (q *Queries) GetPostCategories(ctx context.Context, postID uuid.UUID) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getPostCategories, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostRevisions = `-- name: GetPostRevisions :many
+SELECT id, created_at, post_id, title, url, description, published_at, content_hash, content FROM post_revisions
+WHERE post_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetPostRevisions(ctx context.Context, postID uuid.UUID) ([]PostRevision, error) {
+	rows, err := q.db.QueryContext(ctx, getPostRevisions, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostRevision
+	for rows.Next() {
+		var i PostRevision
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.PostID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.ContentHash,
+			&i.Content,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsByURLKey = `-- name: GetPostsByURLKey :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content_hash, content, author, comments_url, article_content, article_fetched_at, snapshot, archived_at, url_key FROM posts
+WHERE feed_id = $1 AND url_key = $2
+ORDER BY created_at, id
+`
+
+type GetPostsByURLKeyParams struct {
+	FeedID uuid.UUID
+	UrlKey sql.NullString
+}
+
+func (q *Queries) GetPostsByURLKey(ctx context.Context, arg GetPostsByURLKeyParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByURLKey, arg.FeedID, arg.UrlKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Guid,
+			&i.ContentHash,
+			&i.Content,
+			&i.Author,
+			&i.CommentsUrl,
+			&i.ArticleContent,
+			&i.ArticleFetchedAt,
+			&i.Snapshot,
+			&i.ArchivedAt,
+			&i.UrlKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsWithoutURLKey = `-- name: GetPostsWithoutURLKey :many
+SELECT id, url FROM posts
+WHERE url_key IS NULL
+ORDER BY id
+LIMIT $1
+`
+
+type GetPostsWithoutURLKeyRow struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) GetPostsWithoutURLKey(ctx context.Context, limit int32) ([]GetPostsWithoutURLKeyRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsWithoutURLKey, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsWithoutURLKeyRow
+	for rows.Next() {
+		var i GetPostsWithoutURLKeyRow
+		if err := rows.Scan(&i.ID, &i.Url); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markPostRead = `-- name: MarkPostRead :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MarkPostReadParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) MarkPostRead(ctx context.Context, arg MarkPostReadParams) error {
+	_, err := q.db.ExecContext(ctx, markPostRead, arg.UserID, arg.PostID)
+	return err
+}
+
+const markPostUnread = `-- name: MarkPostUnread :exec
+DELETE FROM post_reads
+WHERE user_id = $1 AND post_id = $2
+`
+
+type MarkPostUnreadParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) MarkPostUnread(ctx context.Context, arg MarkPostUnreadParams) error {
+	_, err := q.db.ExecContext(ctx, markPostUnread, arg.UserID, arg.PostID)
+	return err
+}
+
+const markPostUnreadForAll = `-- name: MarkPostUnreadForAll :exec
+DELETE FROM post_reads
+WHERE post_id = $1
+`
+
+func (q *Queries) MarkPostUnreadForAll(ctx context.Context, postID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markPostUnreadForAll, postID)
+	return err
+}
+
+const movePostReads = `-- name: MovePostReads :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT post_reads.user_id, $1, post_reads.read_at
+FROM post_reads
+WHERE post_reads.post_id = $2
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MovePostReadsParams struct {
+	KeeperID    uuid.UUID
+	DuplicateID uuid.UUID
+}
+
+func (q *Queries) MovePostReads(ctx context.Context, arg MovePostReadsParams) error {
+	_, err := q.db.ExecContext(ctx, movePostReads, arg.KeeperID, arg.DuplicateID)
+	return err
+}
+
+const movePostStars = `-- name: MovePostStars :exec
+INSERT INTO post_stars (user_id, post_id, starred_at)
+SELECT post_stars.user_id, $1, post_stars.starred_at
+FROM post_stars
+WHERE post_stars.post_id = $2
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MovePostStarsParams struct {
+	KeeperID    uuid.UUID
+	DuplicateID uuid.UUID
+}
+
+func (q *Queries) MovePostStars(ctx context.Context, arg MovePostStarsParams) error {
+	_, err := q.db.ExecContext(ctx, movePostStars, arg.KeeperID, arg.DuplicateID)
+	return err
+}
+
+const movePostTags = `-- name: MovePostTags :exec
+INSERT INTO post_tags (user_id, post_id, tag, created_at)
+SELECT post_tags.user_id, $1, post_tags.tag, post_tags.created_at
+FROM post_tags
+WHERE post_tags.post_id = $2
+ON CONFLICT (user_id, post_id, tag) DO NOTHING
+`
+
+type MovePostTagsParams struct {
+	KeeperID    uuid.UUID
+	DuplicateID uuid.UUID
+}
+
+func (q *Queries) MovePostTags(ctx context.Context, arg MovePostTagsParams) error {
+	_, err := q.db.ExecContext(ctx, movePostTags, arg.KeeperID, arg.DuplicateID)
+	return err
+}
+
+const setPostArticle = `-- name: SetPostArticle :exec
+UPDATE posts
+SET article_content = $2, article_fetched_at = NOW()
+WHERE id = $1
+`
+
+type SetPostArticleParams struct {
+	ID             uuid.UUID
+	ArticleContent sql.NullString
+}
+
+func (q *Queries) SetPostArticle(ctx context.Context, arg SetPostArticleParams) error {
+	_, err := q.db.ExecContext(ctx, setPostArticle, arg.ID, arg.ArticleContent)
+	return err
+}
+
+const setPostContentHash = `-- name: SetPostContentHash :exec
+UPDATE posts
+SET content_hash = $2
+WHERE id = $1
+`
+
+type SetPostContentHashParams struct {
+	ID          uuid.UUID
+	ContentHash string
+}
+
+func (q *Queries) SetPostContentHash(ctx context.Context, arg SetPostContentHashParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContentHash, arg.ID, arg.ContentHash)
+	return err
+}
+
+const setPostURL = `-- name: SetPostURL :exec
+UPDATE posts
+SET url = $2, url_key = $3
+WHERE id = $1
+`
+
+type SetPostURLParams struct {
+	ID     uuid.UUID
+	Url    string
+	UrlKey sql.NullString
+}
+
+func (q *Queries) SetPostURL(ctx context.Context, arg SetPostURLParams) error {
+	_, err := q.db.ExecContext(ctx, setPostURL, arg.ID, arg.Url, arg.UrlKey)
+	return err
+}
+
+const updatePostContent = `-- name: UpdatePostContent :exec
+UPDATE posts
+SET
+    title = $2,
+    url = $3,
+    description = $4,
+    published_at = $5,
+    content_hash = $6,
+    content = $7,
+    author = $8,
+    comments_url = $9,
+    updated_at = $10,
+    url_key = $11
+WHERE id = $1
+`
+
+type UpdatePostContentParams struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	ContentHash string
+	Content     sql.NullString
+	Author      sql.NullString
+	CommentsUrl sql.NullString
+	UpdatedAt   time.Time
+	UrlKey      sql.NullString
+}
+
+func (q *Queries) UpdatePostContent(ctx context.Context, arg UpdatePostContentParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostContent,
+		arg.ID,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.ContentHash,
+		arg.Content,
+		arg.Author,
+		arg.CommentsUrl,
+		arg.UpdatedAt,
+		arg.UrlKey,
+	)
+	return err
+}
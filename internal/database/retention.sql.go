@@ -0,0 +1,176 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: retention.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const getPrunablePosts = `-- name: GetPrunablePosts :many
+WITH ranked AS (
+    SELECT
+        posts.id,
+        posts.feed_id,
+        COALESCE(posts.published_at, posts.created_at) AS posted_at,
+        ROW_NUMBER() OVER (
+            PARTITION BY posts.feed_id
+            ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id
+        ) AS position,
+        COALESCE(feeds.retention_days, $2::int) AS keep_days,
+        COALESCE(feeds.retention_items, $3::int) AS keep_items
+    FROM posts
+    INNER JOIN feeds ON posts.feed_id = feeds.id
+    WHERE $4::uuid IS NULL OR posts.feed_id = $4::uuid
+)
+SELECT ranked.id, ranked.feed_id, feeds.name AS feed_name
+FROM ranked
+INNER JOIN feeds ON ranked.feed_id = feeds.id
+WHERE (
+    (ranked.keep_days > 0 AND ranked.posted_at < NOW() - make_interval(days => ranked.keep_days))
+    OR (ranked.keep_items > 0 AND ranked.position > ranked.keep_items)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_stars WHERE post_stars.post_id = ranked.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_tags WHERE post_tags.post_id = ranked.id
+)
+AND (NOT $1::boolean OR NOT EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = ranked.feed_id
+    AND NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = ranked.id AND post_reads.user_id = feed_follows.user_id
+    )
+))
+ORDER BY feeds.name, ranked.posted_at
+`
+
+type GetPrunablePostsParams struct {
+	KeepUnread     bool
+	RetentionDays  int32
+	RetentionItems int32
+	FeedID         uuid.NullUUID
+}
+
+type GetPrunablePostsRow struct {
+	ID       uuid.UUID
+	FeedID   uuid.UUID
+	FeedName string
+}
+
+// Starred and tagged posts are kept
+// Keep posts some follower has not read yet
+func (q *Queries) GetPrunablePosts(ctx context.Context, arg GetPrunablePostsParams) ([]GetPrunablePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPrunablePosts,
+		arg.KeepUnread,
+		arg.RetentionDays,
+		arg.RetentionItems,
+		arg.FeedID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPrunablePostsRow
+	for rows.Next() {
+		var i GetPrunablePostsRow
+		if err := rows.Scan(&i.ID, &i.FeedID, &i.FeedName); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isPostPruned = `-- name: IsPostPruned :one
+SELECT EXISTS (
+    SELECT 1 FROM pruned_posts
+    WHERE feed_id = $1 AND guid = $2
+)::boolean
+`
+
+type IsPostPrunedParams struct {
+	FeedID uuid.UUID
+	Guid   string
+}
+
+func (q *Queries) IsPostPruned(ctx context.Context, arg IsPostPrunedParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isPostPruned, arg.FeedID, arg.Guid)
+	var column_1 bool
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const prunePosts = `-- name: PrunePosts :execrows
+WITH deleted AS (
+    DELETE FROM posts
+    WHERE posts.id = ANY($1::uuid[])
+    RETURNING posts.feed_id, posts.guid
+)
+INSERT INTO pruned_posts (feed_id, guid, pruned_at)
+SELECT deleted.feed_id, deleted.guid, NOW() FROM deleted
+ON CONFLICT (feed_id, guid) DO NOTHING
+`
+
+func (q *Queries) PrunePosts(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, prunePosts, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :one
+UPDATE feeds
+SET retention_days = $2, retention_items = $3, updated_at = NOW()
+WHERE url = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, last_fetch_error, deactivated_at, title, description, site_url, language, image_url, extract_full_text, retention_days, retention_items, url_key, orphaned_at
+`
+
+type SetFeedRetentionParams struct {
+	Url            string
+	RetentionDays  sql.NullInt32
+	RetentionItems sql.NullInt32
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedRetention, arg.Url, arg.RetentionDays, arg.RetentionItems)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.LastFetchError,
+		&i.DeactivatedAt,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.ExtractFullText,
+		&i.RetentionDays,
+		&i.RetentionItems,
+		&i.UrlKey,
+		&i.OrphanedAt,
+	)
+	return i, err
+}
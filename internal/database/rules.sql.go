@@ -0,0 +1,166 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: rules.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createRule = `-- name: CreateRule :one
+INSERT INTO rules (id, created_at, user_id, field, match_type, pattern, action, action_arg)
+VALUES ($1, NOW(), $2, $3, $4, $5, $6, $7)
+RETURNING id, created_at, user_id, field, match_type, pattern, action, action_arg
+`
+
+type CreateRuleParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Action    string
+	ActionArg sql.NullString
+}
+
+func (q *Queries) CreateRule(ctx context.Context, arg CreateRuleParams) (Rule, error) {
+	row := q.db.QueryRowContext(ctx, createRule,
+		arg.ID,
+		arg.UserID,
+		arg.Field,
+		arg.MatchType,
+		arg.Pattern,
+		arg.Action,
+		arg.ActionArg,
+	)
+	var i Rule
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.Field,
+		&i.MatchType,
+		&i.Pattern,
+		&i.Action,
+		&i.ActionArg,
+	)
+	return i, err
+}
+
+const deleteRule = `-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteRuleParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteRule(ctx context.Context, arg DeleteRuleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteRule, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getRulesForFeed = `-- name: GetRulesForFeed :many
+SELECT
+    rules.id, rules.created_at, rules.user_id, rules.field, rules.match_type, rules.pattern, rules.action, rules.action_arg,
+    COALESCE(feed_follows.title_override, feeds.name)::text AS feed_name
+FROM rules
+INNER JOIN feed_follows ON feed_follows.user_id = rules.user_id
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.feed_id = $1
+ORDER BY rules.created_at
+`
+
+type GetRulesForFeedRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Action    string
+	ActionArg sql.NullString
+	FeedName  string
+}
+
+func (q *Queries) GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]GetRulesForFeedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRulesForFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRulesForFeedRow
+	for rows.Next() {
+		var i GetRulesForFeedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.Field,
+			&i.MatchType,
+			&i.Pattern,
+			&i.Action,
+			&i.ActionArg,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRulesForUser = `-- name: GetRulesForUser :many
+SELECT id, created_at, user_id, field, match_type, pattern, action, action_arg FROM rules
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]Rule, error) {
+	rows, err := q.db.QueryContext(ctx, getRulesForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Rule
+	for rows.Next() {
+		var i Rule
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.Field,
+			&i.MatchType,
+			&i.Pattern,
+			&i.Action,
+			&i.ActionArg,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
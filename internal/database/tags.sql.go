@@ -0,0 +1,256 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: tags.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const getTaggedPostsForUser = `-- name: GetTaggedPostsForUser :many
+SELECT
+    posts.id,
+    posts.created_at,
+    posts.updated_at,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    posts.feed_id,
+    posts.content,
+    posts.author,
+    posts.comments_url,
+    COALESCE((
+        SELECT string_agg(post_categories.name, ', ' ORDER BY post_categories.name)
+        FROM post_categories
+        WHERE post_categories.post_id = posts.id
+    ), '')::text AS categories,
+    COALESCE((
+        SELECT string_agg(post_tags.tag, ', ' ORDER BY post_tags.tag)
+        FROM post_tags
+        WHERE post_tags.post_id = posts.id AND post_tags.user_id = $1
+    ), '')::text AS tags,
+    COALESCE(feed_follows.title_override, feeds.name)::text AS feed_name,
+    (post_reads.read_at IS NOT NULL)::boolean AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id AND post_stars.user_id = $1
+    )::boolean AS is_starred,
+    posts.snapshot
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN feed_follows ON feed_follows.feed_id = feeds.id AND feed_follows.user_id = $1
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = $1
+WHERE EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.post_id = posts.id
+    AND post_tags.user_id = $1
+    AND ($3::text IS NULL OR post_tags.tag = lower($3::text))
+)
+AND ($4::uuid IS NULL OR feed_follows.folder_id = $4::uuid)
+AND ($5::text IS NULL OR posts.author ILIKE '%' || $5::text || '%')
+AND ($6::text IS NULL OR EXISTS (
+    SELECT 1 FROM post_categories
+    WHERE post_categories.post_id = posts.id
+    AND lower(post_categories.name) = lower($6::text)
+))
+AND ($7::text IS NULL OR (
+    posts.title ILIKE '%' || $7::text || '%'
+    OR posts.description ILIKE '%' || $7::text || '%'
+    OR posts.content ILIKE '%' || $7::text || '%'
+    OR posts.author ILIKE '%' || $7::text || '%'
+    OR EXISTS (
+        SELECT 1 FROM post_categories
+        WHERE post_categories.post_id = posts.id
+        AND post_categories.name ILIKE '%' || $7::text || '%'
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_tags
+        WHERE post_tags.post_id = posts.id
+        AND post_tags.user_id = $1
+        AND post_tags.tag ILIKE '%' || $7::text || '%'
+    )
+))
+ORDER BY COALESCE(posts.published_at, posts.created_at)
+    + make_interval(hours => LEAST(GREATEST(COALESCE(feed_follows.priority, 0), -24), 24)) DESC,
+    COALESCE(feed_follows.priority, 0) DESC
+LIMIT $2
+`
+
+type GetTaggedPostsForUserParams struct {
+	UserID   uuid.UUID
+	Limit    int32
+	Tag      sql.NullString
+	FolderID uuid.NullUUID
+	Author   sql.NullString
+	Category sql.NullString
+	Query    sql.NullString
+}
+
+type GetTaggedPostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	Author      sql.NullString
+	CommentsUrl sql.NullString
+	Categories  string
+	Tags        string
+	FeedName    string
+	IsRead      bool
+	IsStarred   bool
+	Snapshot    sql.NullString
+}
+
+// Same columns as GetPostsForUser; tagged posts stay listed after an unfollow
+// or mute. Without a tag every tagged post is returned.
+// Newest first; each point of priority moves a feed's posts up by an hour, at most a day
+func (q *Queries) GetTaggedPostsForUser(ctx context.Context, arg GetTaggedPostsForUserParams) ([]GetTaggedPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTaggedPostsForUser,
+		arg.UserID,
+		arg.Limit,
+		arg.Tag,
+		arg.FolderID,
+		arg.Author,
+		arg.Category,
+		arg.Query,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTaggedPostsForUserRow
+	for rows.Next() {
+		var i GetTaggedPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.Author,
+			&i.CommentsUrl,
+			&i.Categories,
+			&i.Tags,
+			&i.FeedName,
+			&i.IsRead,
+			&i.IsStarred,
+			&i.Snapshot,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTagsForUser = `-- name: GetTagsForUser :many
+SELECT tag, COUNT(*) AS post_count
+FROM post_tags
+WHERE user_id = $1
+GROUP BY tag
+ORDER BY tag
+`
+
+type GetTagsForUserRow struct {
+	Tag       string
+	PostCount int64
+}
+
+func (q *Queries) GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]GetTagsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTagsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTagsForUserRow
+	for rows.Next() {
+		var i GetTagsForUserRow
+		if err := rows.Scan(&i.Tag, &i.PostCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const tagPost = `-- name: TagPost :exec
+INSERT INTO post_tags (user_id, post_id, tag, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (user_id, post_id, tag) DO NOTHING
+`
+
+type TagPostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	Tag    string
+}
+
+func (q *Queries) TagPost(ctx context.Context, arg TagPostParams) error {
+	_, err := q.db.ExecContext(ctx, tagPost, arg.UserID, arg.PostID, arg.Tag)
+	return err
+}
+
+const untagPost = `-- name: UntagPost :execrows
+DELETE FROM post_tags
+WHERE user_id = $1 AND post_id = $2 AND tag = $3
+`
+
+type UntagPostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	Tag    string
+}
+
+func (q *Queries) UntagPost(ctx context.Context, arg UntagPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, untagPost, arg.UserID, arg.PostID, arg.Tag)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const untagPostAll = `-- name: UntagPostAll :execrows
+DELETE FROM post_tags
+WHERE user_id = $1 AND post_id = $2
+`
+
+type UntagPostAllParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) UntagPostAll(ctx context.Context, arg UntagPostAllParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, untagPostAll, arg.UserID, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
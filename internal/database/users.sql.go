@@ -14,34 +14,51 @@ import (
 )
 
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, title, description, site_url, language, image_url, url_key)
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12
 )
-RETURNING id, created_at, updated_at, name, url, user_id
+RETURNING id, created_at, updated_at, name, url, user_id, title, description, site_url, language, image_url
 `
 
 type CreateFeedParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	UserID    uuid.UUID
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Name        string
+	Url         string
+	UserID      uuid.UUID
+	Title       sql.NullString
+	Description sql.NullString
+	SiteUrl     sql.NullString
+	Language    sql.NullString
+	ImageUrl    sql.NullString
+	UrlKey      sql.NullString
 }
 
 type CreateFeedRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	UserID    uuid.UUID
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Name        string
+	Url         string
+	UserID      uuid.UUID
+	Title       sql.NullString
+	Description sql.NullString
+	SiteUrl     sql.NullString
+	Language    sql.NullString
+	ImageUrl    sql.NullString
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (CreateFeedRow, error) {
@@ -52,6 +69,12 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (CreateF
 		arg.Name,
 		arg.Url,
 		arg.UserID,
+		arg.Title,
+		arg.Description,
+		arg.SiteUrl,
+		arg.Language,
+		arg.ImageUrl,
+		arg.UrlKey,
 	)
 	var i CreateFeedRow
 	err := row.Scan(
@@ -61,6 +84,11 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (CreateF
 		&i.Name,
 		&i.Url,
 		&i.UserID,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
 	)
 	return i, err
 }
@@ -76,7 +104,7 @@ WITH inserted_feed_follow AS (
     ) VALUES (
         $1, $2, $3, $4, $5
     )
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, title_override, priority, muted, notify
 )
 SELECT
     inserted_feed_follow.id,
@@ -139,7 +167,13 @@ INSERT INTO posts (
     url,
     description,
     published_at,
-    feed_id)
+    feed_id,
+    guid,
+    content_hash,
+    content,
+    author,
+    comments_url,
+    url_key)
 VALUES (
     $1,
     $2,
@@ -148,9 +182,15 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13,
+    $14
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content_hash, content, author, comments_url, article_content, article_fetched_at, snapshot, archived_at, url_key
 `
 
 type CreatePostParams struct {
@@ -162,6 +202,12 @@ type CreatePostParams struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Guid        string
+	ContentHash string
+	Content     sql.NullString
+	Author      sql.NullString
+	CommentsUrl sql.NullString
+	UrlKey      sql.NullString
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -174,6 +220,12 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Guid,
+		arg.ContentHash,
+		arg.Content,
+		arg.Author,
+		arg.CommentsUrl,
+		arg.UrlKey,
 	)
 	var i Post
 	err := row.Scan(
@@ -185,19 +237,30 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Guid,
+		&i.ContentHash,
+		&i.Content,
+		&i.Author,
+		&i.CommentsUrl,
+		&i.ArticleContent,
+		&i.ArticleFetchedAt,
+		&i.Snapshot,
+		&i.ArchivedAt,
+		&i.UrlKey,
 	)
 	return i, err
 }
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, is_admin)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    NOT EXISTS (SELECT 1 FROM users)
 )
-RETURNING id, created_at, updated_at, name
+RETURNING id, created_at, updated_at, name, is_admin
 `
 
 type CreateUserParams struct {
@@ -207,6 +270,7 @@ type CreateUserParams struct {
 	Name      string
 }
 
+// The first user becomes the admin
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
 	row := q.db.QueryRowContext(ctx, createUser,
 		arg.ID,
@@ -220,10 +284,52 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.IsAdmin,
 	)
 	return i, err
 }
 
+const deactivateFeed = `-- name: DeactivateFeed :exec
+UPDATE feeds
+SET
+    deactivated_at = NOW(),
+    last_fetched_at = NOW(),
+    last_fetch_error = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type DeactivateFeedParams struct {
+	ID             uuid.UUID
+	LastFetchError sql.NullString
+}
+
+func (q *Queries) DeactivateFeed(ctx context.Context, arg DeactivateFeedParams) error {
+	_, err := q.db.ExecContext(ctx, deactivateFeed, arg.ID, arg.LastFetchError)
+	return err
+}
+
+const deferFeedFetch = `-- name: DeferFeedFetch :exec
+UPDATE feeds
+SET
+    last_fetched_at = NOW(),
+    next_fetch_at = $2,
+    last_fetch_error = $3,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type DeferFeedFetchParams struct {
+	ID             uuid.UUID
+	NextFetchAt    sql.NullTime
+	LastFetchError sql.NullString
+}
+
+func (q *Queries) DeferFeedFetch(ctx context.Context, arg DeferFeedFetchParams) error {
+	_, err := q.db.ExecContext(ctx, deferFeedFetch, arg.ID, arg.NextFetchAt, arg.LastFetchError)
+	return err
+}
+
 const deleteAllUsers = `-- name: DeleteAllUsers :exec
 DELETE FROM users
 `
@@ -233,28 +339,211 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 	return err
 }
 
-const deleteFeedFollowByURL = `-- name: DeleteFeedFollowByURL :exec
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const deleteFeedFollowByURL = `-- name: DeleteFeedFollowByURL :execrows
 DELETE FROM feed_follows
-WHERE feed_follows.user_id = $1 
-AND feed_follows.feed_id IN (SELECT id FROM feeds WHERE url = $2)
+WHERE feed_follows.user_id = $1
+AND feed_follows.feed_id IN (
+    SELECT id FROM feeds
+    WHERE url_key = $2
+    OR (url_key IS NULL AND url = $3)
+)
 `
 
 type DeleteFeedFollowByURLParams struct {
 	UserID uuid.UUID
+	UrlKey sql.NullString
 	Url    string
 }
 
-func (q *Queries) DeleteFeedFollowByURL(ctx context.Context, arg DeleteFeedFollowByURLParams) error {
-	_, err := q.db.ExecContext(ctx, deleteFeedFollowByURL, arg.UserID, arg.Url)
+func (q *Queries) DeleteFeedFollowByURL(ctx context.Context, arg DeleteFeedFollowByURLParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedFollowByURL, arg.UserID, arg.UrlKey, arg.Url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1
+`
+
+// Also deletes the user's follows, folders and rules. Feeds they added are
+// only deleted with them when there is nobody left to hand them to.
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
 	return err
 }
 
+const ensureAdmin = `-- name: EnsureAdmin :exec
+UPDATE users SET is_admin = TRUE
+WHERE users.id = (
+    SELECT other.id FROM users other
+    WHERE other.id <> $1
+    ORDER BY other.created_at, other.id
+    LIMIT 1
+)
+AND NOT EXISTS (
+    SELECT 1 FROM users admins
+    WHERE admins.is_admin AND admins.id <> $1
+)
+`
+
+// Before the given user is deleted: if they are the last admin, the
+// longest-registered other user takes over
+func (q *Queries) EnsureAdmin(ctx context.Context, deletedID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, ensureAdmin, deletedID)
+	return err
+}
+
+const getAllFeeds = `-- name: GetAllFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, last_fetch_error, deactivated_at, title, description, site_url, language, image_url, extract_full_text, retention_days, retention_items, url_key, orphaned_at FROM feeds
+ORDER BY created_at, id
+`
+
+func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getAllFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.NextFetchAt,
+			&i.LastFetchError,
+			&i.DeactivatedAt,
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.ExtractFullText,
+			&i.RetentionDays,
+			&i.RetentionItems,
+			&i.UrlKey,
+			&i.OrphanedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getConflictingFeedPosts = `-- name: GetConflictingFeedPosts :many
+SELECT source.id AS duplicate_id, target.id AS keeper_id
+FROM posts source
+INNER JOIN posts target
+ON target.guid = source.guid AND target.feed_id = $1
+WHERE source.feed_id = $2
+`
+
+type GetConflictingFeedPostsParams struct {
+	TargetFeedID uuid.UUID
+	SourceFeedID uuid.UUID
+}
+
+type GetConflictingFeedPostsRow struct {
+	DuplicateID uuid.UUID
+	KeeperID    uuid.UUID
+}
+
+// Posts of the source feed that MergeFeedPosts leaves behind because the
+// target already has their GUID, paired with the target's copy
+func (q *Queries) GetConflictingFeedPosts(ctx context.Context, arg GetConflictingFeedPostsParams) ([]GetConflictingFeedPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getConflictingFeedPosts, arg.TargetFeedID, arg.SourceFeedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetConflictingFeedPostsRow
+	for rows.Next() {
+		var i GetConflictingFeedPostsRow
+		if err := rows.Scan(&i.DuplicateID, &i.KeeperID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, last_fetch_error, deactivated_at, title, description, site_url, language, image_url, extract_full_text, retention_days, retention_items, url_key, orphaned_at FROM feeds WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.LastFetchError,
+		&i.DeactivatedAt,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.ExtractFullText,
+		&i.RetentionDays,
+		&i.RetentionItems,
+		&i.UrlKey,
+		&i.OrphanedAt,
+	)
+	return i, err
+}
+
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, last_fetch_error, deactivated_at, title, description, site_url, language, image_url, extract_full_text, retention_days, retention_items, url_key, orphaned_at FROM feeds
+WHERE url_key = $1
+OR (url_key IS NULL AND url = $2)
+ORDER BY url_key NULLS LAST
+LIMIT 1
 `
 
-func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getFeedByURL, url)
+type GetFeedByURLParams struct {
+	UrlKey sql.NullString
+	Url    string
+}
+
+// Feeds without a url_key predate URL canonicalization and only match exactly
+func (q *Queries) GetFeedByURL(ctx context.Context, arg GetFeedByURLParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByURL, arg.UrlKey, arg.Url)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -264,6 +553,47 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.LastFetchError,
+		&i.DeactivatedAt,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.ExtractFullText,
+		&i.RetentionDays,
+		&i.RetentionItems,
+		&i.UrlKey,
+		&i.OrphanedAt,
+	)
+	return i, err
+}
+
+const getFeedFollow = `-- name: GetFeedFollow :one
+SELECT id, created_at, updated_at, user_id, feed_id, folder_id, title_override, priority, muted, notify FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type GetFeedFollowParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollow, arg.UserID, arg.FeedID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.FolderID,
+		&i.TitleOverride,
+		&i.Priority,
+		&i.Muted,
+		&i.Notify,
 	)
 	return i, err
 }
@@ -275,22 +605,40 @@ SELECT
     feed_follows.updated_at,
     feed_follows.user_id,
     feed_follows.feed_id,
+    feed_follows.folder_id,
+    feed_follows.title_override,
+    feed_follows.priority,
+    feed_follows.muted,
+    feed_follows.notify,
     users.name AS user_name,
-    feeds.name AS feed_name
+    COALESCE(feed_follows.title_override, feeds.name)::text AS feed_name,
+    feeds.url AS feed_url,
+    feeds.site_url AS feed_site_url,
+    folders.name AS folder_name
 FROM feed_follows
 INNER JOIN users ON feed_follows.user_id = users.id
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
 WHERE feed_follows.user_id = $1
+ORDER BY folders.name NULLS FIRST, feed_follows.priority DESC, feed_name
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	UserName  string
-	FeedName  string
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        uuid.UUID
+	FeedID        uuid.UUID
+	FolderID      uuid.NullUUID
+	TitleOverride sql.NullString
+	Priority      int32
+	Muted         bool
+	Notify        bool
+	UserName      string
+	FeedName      string
+	FeedUrl       string
+	FeedSiteUrl   sql.NullString
+	FolderName    sql.NullString
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -308,8 +656,16 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.FolderID,
+			&i.TitleOverride,
+			&i.Priority,
+			&i.Muted,
+			&i.Notify,
 			&i.UserName,
 			&i.FeedName,
+			&i.FeedUrl,
+			&i.FeedSiteUrl,
+			&i.FolderName,
 		); err != nil {
 			return nil, err
 		}
@@ -324,17 +680,43 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	return items, nil
 }
 
+const getFeedNameForUser = `-- name: GetFeedNameForUser :one
+SELECT COALESCE(feed_follows.title_override, feeds.name)::text AS name
+FROM feeds
+LEFT JOIN feed_follows ON feed_follows.feed_id = feeds.id AND feed_follows.user_id = $1
+WHERE feeds.id = $2
+`
+
+type GetFeedNameForUserParams struct {
+	UserID uuid.NullUUID
+	FeedID uuid.UUID
+}
+
+// The user's title for the feed when they follow it and set one
+func (q *Queries) GetFeedNameForUser(ctx context.Context, arg GetFeedNameForUserParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, getFeedNameForUser, arg.UserID, arg.FeedID)
+	var name string
+	err := row.Scan(&name)
+	return name, err
+}
+
 const getFeeds = `-- name: GetFeeds :many
-SELECT feeds.name, feeds.url, users.name AS username
+SELECT feeds.name, feeds.url, feeds.title, feeds.site_url, feeds.next_fetch_at, feeds.last_fetch_error, feeds.deactivated_at, feeds.orphaned_at, users.name AS username
 FROM feeds
 INNER JOIN users
 ON users.id = feeds.user_id
 `
 
 type GetFeedsRow struct {
-	Name     string
-	Url      string
-	Username string
+	Name           string
+	Url            string
+	Title          sql.NullString
+	SiteUrl        sql.NullString
+	NextFetchAt    sql.NullTime
+	LastFetchError sql.NullString
+	DeactivatedAt  sql.NullTime
+	OrphanedAt     sql.NullTime
+	Username       string
 }
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
@@ -346,7 +728,17 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	var items []GetFeedsRow
 	for rows.Next() {
 		var i GetFeedsRow
-		if err := rows.Scan(&i.Name, &i.Url, &i.Username); err != nil {
+		if err := rows.Scan(
+			&i.Name,
+			&i.Url,
+			&i.Title,
+			&i.SiteUrl,
+			&i.NextFetchAt,
+			&i.LastFetchError,
+			&i.DeactivatedAt,
+			&i.OrphanedAt,
+			&i.Username,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -361,12 +753,16 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, last_fetch_error, deactivated_at, title, description, site_url, language, image_url, extract_full_text, retention_days, retention_items, url_key, orphaned_at FROM feeds
+WHERE deactivated_at IS NULL
+AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+AND EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
 ORDER BY last_fetched_at NULLS FIRST, created_at ASC
 LIMIT 1
 FOR UPDATE SKIP LOCKED
 `
 
+// Nobody reads feeds without followers
 func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
 	var i Feed
@@ -378,44 +774,171 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.LastFetchError,
+		&i.DeactivatedAt,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.ExtractFullText,
+		&i.RetentionDays,
+		&i.RetentionItems,
+		&i.UrlKey,
+		&i.OrphanedAt,
 	)
 	return i, err
 }
 
+const getOtherFollowers = `-- name: GetOtherFollowers :many
+SELECT users.id, users.name
+FROM feed_follows
+INNER JOIN users ON users.id = feed_follows.user_id
+WHERE feed_follows.feed_id = $1
+AND feed_follows.user_id <> $2
+ORDER BY feed_follows.created_at, users.name
+`
+
+type GetOtherFollowersParams struct {
+	FeedID uuid.UUID
+	UserID uuid.UUID
+}
+
+type GetOtherFollowersRow struct {
+	ID   uuid.UUID
+	Name string
+}
+
+// Users other than the given one following a feed, longest-standing first
+func (q *Queries) GetOtherFollowers(ctx context.Context, arg GetOtherFollowersParams) ([]GetOtherFollowersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getOtherFollowers, arg.FeedID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOtherFollowersRow
+	for rows.Next() {
+		var i GetOtherFollowersRow
+		if err := rows.Scan(&i.ID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPostsForUser = `-- name: GetPostsForUser :many
 SELECT
     posts.id,
+    posts.created_at,
+    posts.updated_at,
     posts.title,
     posts.url,
     posts.description,
     posts.published_at,
     posts.feed_id,
-    feeds.name AS feed_name
+    posts.content,
+    posts.author,
+    posts.comments_url,
+    COALESCE((
+        SELECT string_agg(post_categories.name, ', ' ORDER BY post_categories.name)
+        FROM post_categories
+        WHERE post_categories.post_id = posts.id
+    ), '')::text AS categories,
+    COALESCE((
+        SELECT string_agg(post_tags.tag, ', ' ORDER BY post_tags.tag)
+        FROM post_tags
+        WHERE post_tags.post_id = posts.id AND post_tags.user_id = feed_follows.user_id
+    ), '')::text AS tags,
+    COALESCE(feed_follows.title_override, feeds.name)::text AS feed_name,
+    (post_reads.read_at IS NOT NULL)::boolean AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id AND post_stars.user_id = feed_follows.user_id
+    )::boolean AS is_starred,
+    posts.snapshot
 FROM posts
 INNER JOIN feeds ON posts.feed_id = feeds.id
 INNER JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
 WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
+AND NOT feed_follows.muted
+AND ($3::uuid IS NULL OR feed_follows.folder_id = $3::uuid)
+AND ($4::text IS NULL OR posts.author ILIKE '%' || $4::text || '%')
+AND ($5::text IS NULL OR EXISTS (
+    SELECT 1 FROM post_categories
+    WHERE post_categories.post_id = posts.id
+    AND lower(post_categories.name) = lower($5::text)
+))
+AND ($6::text IS NULL OR (
+    posts.title ILIKE '%' || $6::text || '%'
+    OR posts.description ILIKE '%' || $6::text || '%'
+    OR posts.content ILIKE '%' || $6::text || '%'
+    OR posts.author ILIKE '%' || $6::text || '%'
+    OR EXISTS (
+        SELECT 1 FROM post_categories
+        WHERE post_categories.post_id = posts.id
+        AND post_categories.name ILIKE '%' || $6::text || '%'
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_tags
+        WHERE post_tags.post_id = posts.id
+        AND post_tags.user_id = feed_follows.user_id
+        AND post_tags.tag ILIKE '%' || $6::text || '%'
+    )
+))
+ORDER BY COALESCE(posts.published_at, posts.created_at)
+    + make_interval(hours => LEAST(GREATEST(feed_follows.priority, -24), 24)) DESC,
+    feed_follows.priority DESC
 LIMIT $2
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID   uuid.UUID
+	Limit    int32
+	FolderID uuid.NullUUID
+	Author   sql.NullString
+	Category sql.NullString
+	Query    sql.NullString
 }
 
 type GetPostsForUserRow struct {
 	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 	Title       string
 	Url         string
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Content     sql.NullString
+	Author      sql.NullString
+	CommentsUrl sql.NullString
+	Categories  string
+	Tags        string
 	FeedName    string
+	IsRead      bool
+	IsStarred   bool
+	Snapshot    sql.NullString
 }
 
+// Newest first; each point of priority moves a feed's posts up by an hour, at most a day
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Limit,
+		arg.FolderID,
+		arg.Author,
+		arg.Category,
+		arg.Query,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -425,12 +948,85 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 		var i GetPostsForUserRow
 		if err := rows.Scan(
 			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
 			&i.Title,
 			&i.Url,
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Content,
+			&i.Author,
+			&i.CommentsUrl,
+			&i.Categories,
+			&i.Tags,
+			&i.FeedName,
+			&i.IsRead,
+			&i.IsStarred,
+			&i.Snapshot,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnreadPostsForUser = `-- name: GetUnreadPostsForUser :many
+SELECT
+    posts.title,
+    posts.description,
+    posts.content,
+    posts.author,
+    COALESCE(feed_follows.title_override, feeds.name)::text AS feed_name,
+    COALESCE((
+        SELECT string_agg(post_categories.name, ', ' ORDER BY post_categories.name)
+        FROM post_categories
+        WHERE post_categories.post_id = posts.id
+    ), '')::text AS categories
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND NOT feed_follows.muted
+AND NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = $1
+)
+`
+
+type GetUnreadPostsForUserRow struct {
+	Title       string
+	Description sql.NullString
+	Content     sql.NullString
+	Author      sql.NullString
+	FeedName    string
+	Categories  string
+}
+
+// What hide rules look at, for the unread posts of feeds the user follows
+func (q *Queries) GetUnreadPostsForUser(ctx context.Context, userID uuid.UUID) ([]GetUnreadPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUnreadPostsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnreadPostsForUserRow
+	for rows.Next() {
+		var i GetUnreadPostsForUserRow
+		if err := rows.Scan(
+			&i.Title,
+			&i.Description,
+			&i.Content,
+			&i.Author,
 			&i.FeedName,
+			&i.Categories,
 		); err != nil {
 			return nil, err
 		}
@@ -446,7 +1042,7 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, name FROM users WHERE name = $1
+SELECT id, created_at, updated_at, name, is_admin FROM users WHERE name = $1
 `
 
 func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
@@ -457,10 +1053,29 @@ func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.IsAdmin,
 	)
 	return i, err
 }
 
+const getUserStats = `-- name: GetUserStats :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.user_id = $1) AS subscriptions,
+    (SELECT COUNT(*) FROM feeds WHERE feeds.user_id = $1) AS feeds_added
+`
+
+type GetUserStatsRow struct {
+	Subscriptions int64
+	FeedsAdded    int64
+}
+
+func (q *Queries) GetUserStats(ctx context.Context, userID uuid.UUID) (GetUserStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserStats, userID)
+	var i GetUserStatsRow
+	err := row.Scan(&i.Subscriptions, &i.FeedsAdded)
+	return i, err
+}
+
 const getUsers = `-- name: GetUsers :many
 SELECT name FROM users
 `
@@ -492,6 +1107,8 @@ const markFeedFetched = `-- name: MarkFeedFetched :exec
 UPDATE feeds
 SET 
     last_fetched_at = NOW(),
+    next_fetch_at = NULL,
+    last_fetch_error = NULL,
     updated_at = NOW()
 WHERE id = $1
 `
@@ -500,3 +1117,355 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
 	return err
 }
+
+const mergeFeedFollows = `-- name: MergeFeedFollows :exec
+UPDATE feed_follows
+SET
+    feed_id = $1,
+    updated_at = NOW()
+WHERE feed_follows.feed_id = $2
+AND feed_follows.user_id NOT IN (
+    SELECT existing.user_id FROM feed_follows existing WHERE existing.feed_id = $1
+)
+`
+
+type MergeFeedFollowsParams struct {
+	TargetFeedID uuid.UUID
+	SourceFeedID uuid.UUID
+}
+
+func (q *Queries) MergeFeedFollows(ctx context.Context, arg MergeFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, mergeFeedFollows, arg.TargetFeedID, arg.SourceFeedID)
+	return err
+}
+
+const mergeFeedPosts = `-- name: MergeFeedPosts :exec
+UPDATE posts
+SET feed_id = $1
+WHERE posts.feed_id = $2
+AND posts.guid NOT IN (
+    SELECT existing.guid FROM posts existing WHERE existing.feed_id = $1
+)
+`
+
+type MergeFeedPostsParams struct {
+	TargetFeedID uuid.UUID
+	SourceFeedID uuid.UUID
+}
+
+func (q *Queries) MergeFeedPosts(ctx context.Context, arg MergeFeedPostsParams) error {
+	_, err := q.db.ExecContext(ctx, mergeFeedPosts, arg.TargetFeedID, arg.SourceFeedID)
+	return err
+}
+
+const renameFeed = `-- name: RenameFeed :one
+UPDATE feeds
+SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, last_fetch_error, deactivated_at, title, description, site_url, language, image_url, extract_full_text, retention_days, retention_items, url_key, orphaned_at
+`
+
+type RenameFeedParams struct {
+	ID   uuid.UUID
+	Name string
+}
+
+func (q *Queries) RenameFeed(ctx context.Context, arg RenameFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, renameFeed, arg.ID, arg.Name)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.LastFetchError,
+		&i.DeactivatedAt,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.ExtractFullText,
+		&i.RetentionDays,
+		&i.RetentionItems,
+		&i.UrlKey,
+		&i.OrphanedAt,
+	)
+	return i, err
+}
+
+const renameUser = `-- name: RenameUser :one
+UPDATE users
+SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, is_admin
+`
+
+type RenameUserParams struct {
+	ID   uuid.UUID
+	Name string
+}
+
+func (q *Queries) RenameUser(ctx context.Context, arg RenameUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, renameUser, arg.ID, arg.Name)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const resetFeedFetchState = `-- name: ResetFeedFetchState :exec
+UPDATE feeds
+SET
+    next_fetch_at = NULL,
+    last_fetch_error = NULL,
+    deactivated_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+// A feed given a working URL starts over: no backoff, no deactivation
+func (q *Queries) ResetFeedFetchState(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, resetFeedFetchState, id)
+	return err
+}
+
+const setFeedExtractFullText = `-- name: SetFeedExtractFullText :one
+UPDATE feeds
+SET extract_full_text = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, last_fetch_error, deactivated_at, title, description, site_url, language, image_url, extract_full_text, retention_days, retention_items, url_key, orphaned_at
+`
+
+type SetFeedExtractFullTextParams struct {
+	ID              uuid.UUID
+	ExtractFullText bool
+}
+
+func (q *Queries) SetFeedExtractFullText(ctx context.Context, arg SetFeedExtractFullTextParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedExtractFullText, arg.ID, arg.ExtractFullText)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.LastFetchError,
+		&i.DeactivatedAt,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.ExtractFullText,
+		&i.RetentionDays,
+		&i.RetentionItems,
+		&i.UrlKey,
+		&i.OrphanedAt,
+	)
+	return i, err
+}
+
+const setFeedOwner = `-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedOwnerParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) SetFeedOwner(ctx context.Context, arg SetFeedOwnerParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedOwner, arg.ID, arg.UserID)
+	return err
+}
+
+const transferFeedsToAdmin = `-- name: TransferFeedsToAdmin :execrows
+UPDATE feeds
+SET
+    user_id = (
+        SELECT users.id FROM users
+        WHERE users.is_admin AND users.id <> $1
+        ORDER BY users.created_at, users.id
+        LIMIT 1
+    ),
+    updated_at = NOW()
+WHERE feeds.user_id = $1
+AND EXISTS (SELECT 1 FROM users WHERE users.is_admin AND users.id <> $1)
+`
+
+// Hands the remaining feeds a user added to an admin, so they are not deleted
+// with the user but reaped once their grace period is over
+func (q *Queries) TransferFeedsToAdmin(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, transferFeedsToAdmin, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const transferFollowedFeeds = `-- name: TransferFollowedFeeds :execrows
+UPDATE feeds
+SET
+    user_id = (
+        SELECT feed_follows.user_id FROM feed_follows
+        WHERE feed_follows.feed_id = feeds.id AND feed_follows.user_id <> $1
+        ORDER BY feed_follows.created_at
+        LIMIT 1
+    ),
+    updated_at = NOW()
+WHERE feeds.user_id = $1
+AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id AND feed_follows.user_id <> $1
+)
+`
+
+// Hands the feeds a user added to their longest-standing other follower, so
+// deleting the user does not take them away from everyone else
+func (q *Queries) TransferFollowedFeeds(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, transferFollowedFeeds, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET
+    title = $2,
+    description = $3,
+    site_url = $4,
+    language = $5,
+    image_url = $6,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedMetadataParams struct {
+	ID          uuid.UUID
+	Title       sql.NullString
+	Description sql.NullString
+	SiteUrl     sql.NullString
+	Language    sql.NullString
+	ImageUrl    sql.NullString
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.SiteUrl,
+		arg.Language,
+		arg.ImageUrl,
+	)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :one
+UPDATE feeds
+SET
+    url = $2,
+    url_key = $3,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, last_fetch_error, deactivated_at, title, description, site_url, language, image_url, extract_full_text, retention_days, retention_items, url_key, orphaned_at
+`
+
+type UpdateFeedURLParams struct {
+	ID     uuid.UUID
+	Url    string
+	UrlKey sql.NullString
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedURL, arg.ID, arg.Url, arg.UrlKey)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.LastFetchError,
+		&i.DeactivatedAt,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.ExtractFullText,
+		&i.RetentionDays,
+		&i.RetentionItems,
+		&i.UrlKey,
+		&i.OrphanedAt,
+	)
+	return i, err
+}
+
+const updateFollowSettings = `-- name: UpdateFollowSettings :one
+UPDATE feed_follows
+SET
+    title_override = $3,
+    priority = $4,
+    muted = $5,
+    notify = $6,
+    updated_at = NOW()
+WHERE feed_follows.user_id = $1
+AND feed_follows.feed_id = $2
+RETURNING id, created_at, updated_at, user_id, feed_id, folder_id, title_override, priority, muted, notify
+`
+
+type UpdateFollowSettingsParams struct {
+	UserID        uuid.UUID
+	FeedID        uuid.UUID
+	TitleOverride sql.NullString
+	Priority      int32
+	Muted         bool
+	Notify        bool
+}
+
+func (q *Queries) UpdateFollowSettings(ctx context.Context, arg UpdateFollowSettingsParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, updateFollowSettings,
+		arg.UserID,
+		arg.FeedID,
+		arg.TitleOverride,
+		arg.Priority,
+		arg.Muted,
+		arg.Notify,
+	)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.FolderID,
+		&i.TitleOverride,
+		&i.Priority,
+		&i.Muted,
+		&i.Notify,
+	)
+	return i, err
+}
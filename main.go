@@ -29,9 +29,16 @@ func main() {
     // Создание экземпляра queries
     dbQueries := database.New(db)
 
+	fetcher, err := config.NewFetcher(cfg)
+	if err != nil {
+		log.Fatalf("Invalid HTTP settings in config: %v", err)
+	}
+
 	state := &config.State{
 		DB:		dbQueries,
 		Config:	cfg,
+		Conn:	db,
+		Fetcher:	fetcher,
 	}
 
 	commands := config.NewCommands()
@@ -39,14 +46,43 @@ func main() {
 	commands.Register("register", config.HandlerRegister)
 	commands.Register("reset", config.HandlerReset)
 	commands.Register("users", config.HandlerUsers)
+	commands.Register("whoami", config.MiddlewareLoggedIn(config.HandlerWhoami))
+	commands.Register("renameuser", config.MiddlewareLoggedIn(config.HandlerRenameUser))
+	commands.Register("deleteuser", config.MiddlewareLoggedIn(config.HandlerDeleteUser))
 	commands.Register("agg", config.HandlerAgg)
 	commands.Register("addfeed", config.MiddlewareLoggedIn(config.HandlerAddFeed))
 	commands.Register("feeds", config.HandlerFeeds)
+	commands.Register("editfeed", config.MiddlewareLoggedIn(config.HandlerEditFeed))
+	commands.Register("deletefeed", config.MiddlewareLoggedIn(config.HandlerDeleteFeed))
 	commands.Register("follow", config.MiddlewareLoggedIn(config.HandlerFollow))
 	commands.Register("following", config.MiddlewareLoggedIn(config.HandlerFollowing))
 	commands.Register("unfollow", config.MiddlewareLoggedIn(config.HandlerUnfollow))
+	commands.Register("followset", config.MiddlewareLoggedIn(config.HandlerFollowSet))
 	// commands.Register("browse", config.HandlerBrowse)
 	commands.Register("browse", config.MiddlewareLoggedIn(config.HandlerBrowse))
+	commands.Register("notifications", config.MiddlewareLoggedIn(config.HandlerNotifications))
+	commands.Register("read", config.MiddlewareLoggedIn(config.HandlerRead))
+	commands.Register("unread", config.MiddlewareLoggedIn(config.HandlerUnread))
+	commands.Register("history", config.HandlerHistory)
+	commands.Register("download", config.HandlerDownload)
+	commands.Register("fulltext", config.MiddlewareLoggedIn(config.HandlerFullText))
+	commands.Register("view", config.MiddlewareLoggedIn(config.HandlerView))
+	commands.Register("star", config.MiddlewareLoggedIn(config.HandlerStar))
+	commands.Register("unstar", config.MiddlewareLoggedIn(config.HandlerUnstar))
+	commands.Register("starred", config.MiddlewareLoggedIn(config.HandlerStarred))
+	commands.Register("archive", config.HandlerArchive)
+	commands.Register("prune", config.HandlerPrune)
+	commands.Register("reap", config.HandlerReap)
+	commands.Register("canonicalize", config.HandlerCanonicalize)
+	commands.Register("retention", config.MiddlewareLoggedIn(config.HandlerRetention))
+	commands.Register("folder", config.MiddlewareLoggedIn(config.HandlerFolder))
+	commands.Register("markallread", config.MiddlewareLoggedIn(config.HandlerMarkAllRead))
+	commands.Register("export", config.MiddlewareLoggedIn(config.HandlerExport))
+	commands.Register("tag", config.MiddlewareLoggedIn(config.HandlerTag))
+	commands.Register("untag", config.MiddlewareLoggedIn(config.HandlerUntag))
+	commands.Register("tagged", config.MiddlewareLoggedIn(config.HandlerTagged))
+	commands.Register("search", config.MiddlewareLoggedIn(config.HandlerSearch))
+	commands.Register("rule", config.MiddlewareLoggedIn(config.HandlerRule))
 
 	cmdName := os.Args[1]
 	var cmdArgs []string
@@ -0,0 +1,27 @@
+-- name: CreateAttachment :exec
+INSERT INTO attachments (id, created_at, post_id, kind, url, mime_type, length, duration_seconds, episode)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+ON CONFLICT (post_id, url) DO NOTHING;
+
+-- name: DeletePostAttachments :exec
+DELETE FROM attachments WHERE post_id = $1;
+
+-- name: GetAttachmentsForPost :many
+SELECT
+    attachments.*,
+    feeds.name AS feed_name
+FROM attachments
+INNER JOIN posts ON attachments.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE attachments.post_id = $1
+ORDER BY attachments.created_at ASC, attachments.url ASC;
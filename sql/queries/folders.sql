@@ -0,0 +1,40 @@
+-- name: CreateFolder :one
+INSERT INTO folders (id, created_at, updated_at, user_id, name)
+VALUES ($1, NOW(), NOW(), $2, $3)
+RETURNING *;
+
+-- name: GetFolderByName :one
+SELECT * FROM folders
+WHERE user_id = $1 AND name = $2;
+
+-- name: GetFoldersForUser :many
+SELECT
+    folders.*,
+    COUNT(feed_follows.id) AS feed_count
+FROM folders
+LEFT JOIN feed_follows ON feed_follows.folder_id = folders.id
+WHERE folders.user_id = $1
+GROUP BY folders.id
+ORDER BY folders.name;
+
+-- name: DeleteFolder :exec
+DELETE FROM folders WHERE id = $1;
+
+-- name: SetFollowFolder :execrows
+UPDATE feed_follows
+SET folder_id = sqlc.narg(folder_id), updated_at = NOW()
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND feed_follows.feed_id IN (
+    SELECT id FROM feeds
+    WHERE url_key = sqlc.arg(url_key)
+    OR (url_key IS NULL AND url = sqlc.arg(url))
+);
+
+-- name: MarkAllPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, NOW()
+FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(folder_id)::uuid IS NULL OR feed_follows.folder_id = sqlc.narg(folder_id)::uuid)
+ON CONFLICT (user_id, post_id) DO NOTHING;
@@ -0,0 +1,36 @@
+-- name: NotifyFeedFollowers :exec
+INSERT INTO notifications (id, created_at, user_id, feed_id, message)
+SELECT gen_random_uuid(), NOW(), feed_follows.user_id, feed_follows.feed_id, $2
+FROM feed_follows
+WHERE feed_follows.feed_id = $1;
+
+-- name: GetUnreadNotifications :many
+SELECT * FROM notifications
+WHERE user_id = $1 AND read_at IS NULL
+ORDER BY created_at ASC;
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: NotifyNewPost :exec
+INSERT INTO notifications (id, created_at, user_id, feed_id, post_id, message)
+SELECT
+    gen_random_uuid(),
+    NOW(),
+    feed_follows.user_id,
+    feed_follows.feed_id,
+    sqlc.arg(post_id),
+    'New post in ' || COALESCE(feed_follows.title_override, feeds.name) || ': ' || sqlc.arg(title)::text
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.feed_id = sqlc.arg(feed_id)
+AND feed_follows.notify
+AND NOT feed_follows.muted
+-- Followers whose hide rules match the post
+AND NOT feed_follows.user_id = ANY(sqlc.arg(hidden_for)::uuid[]);
+
+-- name: CreateNotification :exec
+INSERT INTO notifications (id, created_at, user_id, feed_id, post_id, message)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4);
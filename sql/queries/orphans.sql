@@ -0,0 +1,39 @@
+-- name: MarkOrphanedFeeds :exec
+-- Start the grace period of feeds that lost their last follower, and stop it
+-- for feeds that were followed again
+UPDATE feeds
+SET orphaned_at = CASE
+    WHEN EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id) THEN NULL
+    ELSE NOW()
+END
+WHERE (feeds.orphaned_at IS NULL) <> EXISTS (
+    SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id
+);
+
+-- name: GetOrphanedFeeds :many
+SELECT
+    feeds.*,
+    (SELECT COUNT(*) FROM posts WHERE posts.feed_id = feeds.id) AS post_count,
+    (
+        SELECT COUNT(*) FROM posts
+        WHERE posts.feed_id = feeds.id
+        AND (
+            EXISTS (SELECT 1 FROM post_stars WHERE post_stars.post_id = posts.id)
+            OR EXISTS (SELECT 1 FROM post_tags WHERE post_tags.post_id = posts.id)
+        )
+    ) AS saved_count
+FROM feeds
+WHERE feeds.orphaned_at IS NOT NULL
+ORDER BY feeds.orphaned_at, feeds.name;
+
+-- name: GetPostsForFeed :many
+SELECT * FROM posts
+WHERE feed_id = $1
+ORDER BY COALESCE(published_at, created_at), id;
+
+-- name: DeleteUnsavedPosts :execrows
+-- Deletes the posts of a feed nobody starred or tagged
+DELETE FROM posts
+WHERE feed_id = $1
+AND NOT EXISTS (SELECT 1 FROM post_stars WHERE post_stars.post_id = posts.id)
+AND NOT EXISTS (SELECT 1 FROM post_tags WHERE post_tags.post_id = posts.id);
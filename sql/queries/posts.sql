@@ -0,0 +1,122 @@
+-- name: GetPostByGUID :one
+SELECT * FROM posts
+WHERE feed_id = $1 AND guid = $2;
+
+-- name: GetPostByID :one
+SELECT * FROM posts WHERE id = $1;
+
+-- name: CreatePostRevision :exec
+INSERT INTO post_revisions (id, created_at, post_id, title, url, description, published_at, content_hash, content)
+SELECT sqlc.arg(id), NOW(), posts.id, posts.title, posts.url, posts.description, posts.published_at, posts.content_hash, posts.content
+FROM posts
+WHERE posts.id = sqlc.arg(post_id);
+
+-- name: UpdatePostContent :exec
+UPDATE posts
+SET
+    title = $2,
+    url = $3,
+    description = $4,
+    published_at = $5,
+    content_hash = $6,
+    content = $7,
+    author = $8,
+    comments_url = $9,
+    updated_at = $10,
+    url_key = $11
+WHERE id = $1;
+
+-- name: SetPostArticle :exec
+UPDATE posts
+SET article_content = $2, article_fetched_at = NOW()
+WHERE id = $1;
+
+-- name: SetPostContentHash :exec
+UPDATE posts
+SET content_hash = $2
+WHERE id = $1;
+
+-- name: GetPostRevisions :many
+SELECT * FROM post_revisions
+WHERE post_id = $1
+ORDER BY created_at DESC;
+
+-- name: MarkPostRead :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkPostUnread :exec
+DELETE FROM post_reads
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: MarkPostUnreadForAll :exec
+DELETE FROM post_reads
+WHERE post_id = $1;
+
+-- name: MovePostReads :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT post_reads.user_id, sqlc.arg(keeper_id), post_reads.read_at
+FROM post_reads
+WHERE post_reads.post_id = sqlc.arg(duplicate_id)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MovePostStars :exec
+INSERT INTO post_stars (user_id, post_id, starred_at)
+SELECT post_stars.user_id, sqlc.arg(keeper_id), post_stars.starred_at
+FROM post_stars
+WHERE post_stars.post_id = sqlc.arg(duplicate_id)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MovePostTags :exec
+INSERT INTO post_tags (user_id, post_id, tag, created_at)
+SELECT post_tags.user_id, sqlc.arg(keeper_id), post_tags.tag, post_tags.created_at
+FROM post_tags
+WHERE post_tags.post_id = sqlc.arg(duplicate_id)
+ON CONFLICT (user_id, post_id, tag) DO NOTHING;
+
+-- name: DeletePostCategories :exec
+DELETE FROM post_categories WHERE post_id = $1;
+
+-- name: AddPostCategory :exec
+INSERT INTO post_categories (post_id, name)
+VALUES ($1, $2)
+ON CONFLICT (post_id, name) DO NOTHING;
+
+-- name: GetPostCategories :many
+SELECT name FROM post_categories
+WHERE post_id = $1
+ORDER BY name;
+
+-- name: GetPostsWithoutURLKey :many
+SELECT id, url FROM posts
+WHERE url_key IS NULL
+ORDER BY id
+LIMIT $1;
+
+-- name: SetPostURL :exec
+UPDATE posts
+SET url = $2, url_key = $3
+WHERE id = $1;
+
+-- name: GetDuplicateCandidates :many
+-- Posts of one feed sharing a url_key with another, oldest first. Whether
+-- they are the same item is up to their GUIDs.
+SELECT posts.id, posts.feed_id, posts.guid, posts.url_key::text AS url_key
+FROM posts
+WHERE posts.url_key IS NOT NULL AND posts.url_key <> ''
+AND EXISTS (
+    SELECT 1 FROM posts other
+    WHERE other.feed_id = posts.feed_id
+    AND other.url_key = posts.url_key
+    AND other.id <> posts.id
+)
+ORDER BY posts.feed_id, posts.url_key, posts.created_at, posts.id;
+
+-- name: GetPostsByURLKey :many
+SELECT * FROM posts
+WHERE feed_id = $1 AND url_key = $2
+ORDER BY created_at, id;
+
+-- name: DeletePost :exec
+DELETE FROM posts WHERE id = $1;
@@ -0,0 +1,62 @@
+-- name: SetFeedRetention :one
+UPDATE feeds
+SET retention_days = $2, retention_items = $3, updated_at = NOW()
+WHERE url = $1
+RETURNING *;
+
+-- name: GetPrunablePosts :many
+WITH ranked AS (
+    SELECT
+        posts.id,
+        posts.feed_id,
+        COALESCE(posts.published_at, posts.created_at) AS posted_at,
+        ROW_NUMBER() OVER (
+            PARTITION BY posts.feed_id
+            ORDER BY COALESCE(posts.published_at, posts.created_at) DESC, posts.id
+        ) AS position,
+        COALESCE(feeds.retention_days, sqlc.arg(retention_days)::int) AS keep_days,
+        COALESCE(feeds.retention_items, sqlc.arg(retention_items)::int) AS keep_items
+    FROM posts
+    INNER JOIN feeds ON posts.feed_id = feeds.id
+    WHERE sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id)::uuid
+)
+SELECT ranked.id, ranked.feed_id, feeds.name AS feed_name
+FROM ranked
+INNER JOIN feeds ON ranked.feed_id = feeds.id
+WHERE (
+    (ranked.keep_days > 0 AND ranked.posted_at < NOW() - make_interval(days => ranked.keep_days))
+    OR (ranked.keep_items > 0 AND ranked.position > ranked.keep_items)
+)
+-- Starred and tagged posts are kept
+AND NOT EXISTS (
+    SELECT 1 FROM post_stars WHERE post_stars.post_id = ranked.id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM post_tags WHERE post_tags.post_id = ranked.id
+)
+-- Keep posts some follower has not read yet
+AND (NOT sqlc.arg(keep_unread)::boolean OR NOT EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = ranked.feed_id
+    AND NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = ranked.id AND post_reads.user_id = feed_follows.user_id
+    )
+))
+ORDER BY feeds.name, ranked.posted_at;
+
+-- name: PrunePosts :execrows
+WITH deleted AS (
+    DELETE FROM posts
+    WHERE posts.id = ANY(sqlc.arg(ids)::uuid[])
+    RETURNING posts.feed_id, posts.guid
+)
+INSERT INTO pruned_posts (feed_id, guid, pruned_at)
+SELECT deleted.feed_id, deleted.guid, NOW() FROM deleted
+ON CONFLICT (feed_id, guid) DO NOTHING;
+
+-- name: IsPostPruned :one
+SELECT EXISTS (
+    SELECT 1 FROM pruned_posts
+    WHERE feed_id = $1 AND guid = $2
+)::boolean;
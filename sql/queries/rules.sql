@@ -0,0 +1,23 @@
+-- name: CreateRule :one
+INSERT INTO rules (id, created_at, user_id, field, match_type, pattern, action, action_arg)
+VALUES ($1, NOW(), $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetRulesForUser :many
+SELECT * FROM rules
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetRulesForFeed :many
+SELECT
+    rules.*,
+    COALESCE(feed_follows.title_override, feeds.name)::text AS feed_name
+FROM rules
+INNER JOIN feed_follows ON feed_follows.user_id = rules.user_id
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.feed_id = $1
+ORDER BY rules.created_at;
@@ -0,0 +1,91 @@
+-- name: TagPost :exec
+INSERT INTO post_tags (user_id, post_id, tag, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (user_id, post_id, tag) DO NOTHING;
+
+-- name: UntagPost :execrows
+DELETE FROM post_tags
+WHERE user_id = $1 AND post_id = $2 AND tag = $3;
+
+-- name: UntagPostAll :execrows
+DELETE FROM post_tags
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetTagsForUser :many
+SELECT tag, COUNT(*) AS post_count
+FROM post_tags
+WHERE user_id = $1
+GROUP BY tag
+ORDER BY tag;
+
+-- name: GetTaggedPostsForUser :many
+-- Same columns as GetPostsForUser; tagged posts stay listed after an unfollow
+-- or mute. Without a tag every tagged post is returned.
+SELECT
+    posts.id,
+    posts.created_at,
+    posts.updated_at,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    posts.feed_id,
+    posts.content,
+    posts.author,
+    posts.comments_url,
+    COALESCE((
+        SELECT string_agg(post_categories.name, ', ' ORDER BY post_categories.name)
+        FROM post_categories
+        WHERE post_categories.post_id = posts.id
+    ), '')::text AS categories,
+    COALESCE((
+        SELECT string_agg(post_tags.tag, ', ' ORDER BY post_tags.tag)
+        FROM post_tags
+        WHERE post_tags.post_id = posts.id AND post_tags.user_id = $1
+    ), '')::text AS tags,
+    COALESCE(feed_follows.title_override, feeds.name)::text AS feed_name,
+    (post_reads.read_at IS NOT NULL)::boolean AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id AND post_stars.user_id = $1
+    )::boolean AS is_starred,
+    posts.snapshot
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+LEFT JOIN feed_follows ON feed_follows.feed_id = feeds.id AND feed_follows.user_id = $1
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = $1
+WHERE EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.post_id = posts.id
+    AND post_tags.user_id = $1
+    AND (sqlc.narg(tag)::text IS NULL OR post_tags.tag = lower(sqlc.narg(tag)::text))
+)
+AND (sqlc.narg(folder_id)::uuid IS NULL OR feed_follows.folder_id = sqlc.narg(folder_id)::uuid)
+AND (sqlc.narg(author)::text IS NULL OR posts.author ILIKE '%' || sqlc.narg(author)::text || '%')
+AND (sqlc.narg(category)::text IS NULL OR EXISTS (
+    SELECT 1 FROM post_categories
+    WHERE post_categories.post_id = posts.id
+    AND lower(post_categories.name) = lower(sqlc.narg(category)::text)
+))
+AND (sqlc.narg(query)::text IS NULL OR (
+    posts.title ILIKE '%' || sqlc.narg(query)::text || '%'
+    OR posts.description ILIKE '%' || sqlc.narg(query)::text || '%'
+    OR posts.content ILIKE '%' || sqlc.narg(query)::text || '%'
+    OR posts.author ILIKE '%' || sqlc.narg(query)::text || '%'
+    OR EXISTS (
+        SELECT 1 FROM post_categories
+        WHERE post_categories.post_id = posts.id
+        AND post_categories.name ILIKE '%' || sqlc.narg(query)::text || '%'
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_tags
+        WHERE post_tags.post_id = posts.id
+        AND post_tags.user_id = $1
+        AND post_tags.tag ILIKE '%' || sqlc.narg(query)::text || '%'
+    )
+))
+-- Newest first; each point of priority moves a feed's posts up by an hour, at most a day
+ORDER BY COALESCE(posts.published_at, posts.created_at)
+    + make_interval(hours => LEAST(GREATEST(COALESCE(feed_follows.priority, 0), -24), 24)) DESC,
+    COALESCE(feed_follows.priority, 0) DESC
+LIMIT $2;
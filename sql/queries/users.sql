@@ -1,10 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+-- The first user becomes the admin
+INSERT INTO users (id, created_at, updated_at, name, is_admin)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    NOT EXISTS (SELECT 1 FROM users)
 )
 RETURNING *;
 
@@ -17,20 +19,124 @@ DELETE FROM users;
 -- name: GetUsers :many
 SELECT name FROM users;
 
+-- name: GetUserStats :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.user_id = $1) AS subscriptions,
+    (SELECT COUNT(*) FROM feeds WHERE feeds.user_id = $1) AS feeds_added;
+
+-- name: GetUnreadPostsForUser :many
+-- What hide rules look at, for the unread posts of feeds the user follows
+SELECT
+    posts.title,
+    posts.description,
+    posts.content,
+    posts.author,
+    COALESCE(feed_follows.title_override, feeds.name)::text AS feed_name,
+    COALESCE((
+        SELECT string_agg(post_categories.name, ', ' ORDER BY post_categories.name)
+        FROM post_categories
+        WHERE post_categories.post_id = posts.id
+    ), '')::text AS categories
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND NOT feed_follows.muted
+AND NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = $1
+);
+
+-- name: RenameUser :one
+UPDATE users
+SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: EnsureAdmin :exec
+-- Before the given user is deleted: if they are the last admin, the
+-- longest-registered other user takes over
+UPDATE users SET is_admin = TRUE
+WHERE users.id = (
+    SELECT other.id FROM users other
+    WHERE other.id <> sqlc.arg(deleted_id)
+    ORDER BY other.created_at, other.id
+    LIMIT 1
+)
+AND NOT EXISTS (
+    SELECT 1 FROM users admins
+    WHERE admins.is_admin AND admins.id <> sqlc.arg(deleted_id)
+);
+
+-- name: TransferFollowedFeeds :execrows
+-- Hands the feeds a user added to their longest-standing other follower, so
+-- deleting the user does not take them away from everyone else
+UPDATE feeds
+SET
+    user_id = (
+        SELECT feed_follows.user_id FROM feed_follows
+        WHERE feed_follows.feed_id = feeds.id AND feed_follows.user_id <> $1
+        ORDER BY feed_follows.created_at
+        LIMIT 1
+    ),
+    updated_at = NOW()
+WHERE feeds.user_id = $1
+AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id AND feed_follows.user_id <> $1
+);
+
+-- name: TransferFeedsToAdmin :execrows
+-- Hands the remaining feeds a user added to an admin, so they are not deleted
+-- with the user but reaped once their grace period is over
+UPDATE feeds
+SET
+    user_id = (
+        SELECT users.id FROM users
+        WHERE users.is_admin AND users.id <> $1
+        ORDER BY users.created_at, users.id
+        LIMIT 1
+    ),
+    updated_at = NOW()
+WHERE feeds.user_id = $1
+AND EXISTS (SELECT 1 FROM users WHERE users.is_admin AND users.id <> $1);
+
+-- name: DeleteUser :exec
+-- Also deletes the user's follows, folders and rules. Feeds they added are
+-- only deleted with them when there is nobody left to hand them to.
+DELETE FROM users WHERE id = $1;
+
 -- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, title, description, site_url, language, image_url, url_key)
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12
 )
-RETURNING id, created_at, updated_at, name, url, user_id;
+RETURNING id, created_at, updated_at, name, url, user_id, title, description, site_url, language, image_url;
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET
+    title = $2,
+    description = $3,
+    site_url = $4,
+    language = $5,
+    image_url = $6,
+    updated_at = NOW()
+WHERE id = $1;
 
 -- name: GetFeeds :many
-SELECT feeds.name, feeds.url, users.name AS username
+SELECT feeds.name, feeds.url, feeds.title, feeds.site_url, feeds.next_fetch_at, feeds.last_fetch_error, feeds.deactivated_at, feeds.orphaned_at, users.name AS username
 FROM feeds
 INNER JOIN users
 ON users.id = feeds.user_id;
@@ -61,7 +167,21 @@ INNER JOIN users ON inserted_feed_follow.user_id = users.id
 INNER JOIN feeds ON inserted_feed_follow.feed_id = feeds.id;
 
 -- name: GetFeedByURL :one
-SELECT * FROM feeds WHERE url = $1;
+-- Feeds without a url_key predate URL canonicalization and only match exactly
+SELECT * FROM feeds
+WHERE url_key = sqlc.arg(url_key)
+OR (url_key IS NULL AND url = sqlc.arg(url))
+ORDER BY url_key NULLS LAST
+LIMIT 1;
+
+-- name: GetFeedByID :one
+SELECT * FROM feeds WHERE id = $1;
+
+-- name: SetFeedExtractFullText :one
+UPDATE feeds
+SET extract_full_text = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
 
 -- name: GetFeedFollowsForUser :many
 SELECT 
@@ -70,32 +190,166 @@ SELECT
     feed_follows.updated_at,
     feed_follows.user_id,
     feed_follows.feed_id,
+    feed_follows.folder_id,
+    feed_follows.title_override,
+    feed_follows.priority,
+    feed_follows.muted,
+    feed_follows.notify,
     users.name AS user_name,
-    feeds.name AS feed_name
+    COALESCE(feed_follows.title_override, feeds.name)::text AS feed_name,
+    feeds.url AS feed_url,
+    feeds.site_url AS feed_site_url,
+    folders.name AS folder_name
 FROM feed_follows
 INNER JOIN users ON feed_follows.user_id = users.id
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
-WHERE feed_follows.user_id = $1;
+LEFT JOIN folders ON feed_follows.folder_id = folders.id
+WHERE feed_follows.user_id = $1
+ORDER BY folders.name NULLS FIRST, feed_follows.priority DESC, feed_name;
+
+-- name: UpdateFollowSettings :one
+UPDATE feed_follows
+SET
+    title_override = $3,
+    priority = $4,
+    muted = $5,
+    notify = $6,
+    updated_at = NOW()
+WHERE feed_follows.user_id = $1
+AND feed_follows.feed_id = $2
+RETURNING *;
+
+-- name: GetFeedFollow :one
+SELECT * FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: GetFeedNameForUser :one
+-- The user's title for the feed when they follow it and set one
+SELECT COALESCE(feed_follows.title_override, feeds.name)::text AS name
+FROM feeds
+LEFT JOIN feed_follows ON feed_follows.feed_id = feeds.id AND feed_follows.user_id = sqlc.narg(user_id)
+WHERE feeds.id = sqlc.arg(feed_id);
 
 
--- name: DeleteFeedFollowByURL :exec
+-- name: DeleteFeedFollowByURL :execrows
 DELETE FROM feed_follows
-WHERE feed_follows.user_id = $1 
-AND feed_follows.feed_id IN (SELECT id FROM feeds WHERE url = $2);
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND feed_follows.feed_id IN (
+    SELECT id FROM feeds
+    WHERE url_key = sqlc.arg(url_key)
+    OR (url_key IS NULL AND url = sqlc.arg(url))
+);
 
 -- name: MarkFeedFetched :exec
 UPDATE feeds
 SET 
     last_fetched_at = NOW(),
+    next_fetch_at = NULL,
+    last_fetch_error = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: ResetFeedFetchState :exec
+-- A feed given a working URL starts over: no backoff, no deactivation
+UPDATE feeds
+SET
+    next_fetch_at = NULL,
+    last_fetch_error = NULL,
+    deactivated_at = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeferFeedFetch :exec
+UPDATE feeds
+SET
+    last_fetched_at = NOW(),
+    next_fetch_at = $2,
+    last_fetch_error = $3,
     updated_at = NOW()
 WHERE id = $1;
 
 -- name: GetNextFeedToFetch :one
 SELECT * FROM feeds
+WHERE deactivated_at IS NULL
+AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+-- Nobody reads feeds without followers
+AND EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
 ORDER BY last_fetched_at NULLS FIRST, created_at ASC
 LIMIT 1
 FOR UPDATE SKIP LOCKED;
 
+-- name: UpdateFeedURL :one
+UPDATE feeds
+SET
+    url = $2,
+    url_key = $3,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetAllFeeds :many
+SELECT * FROM feeds
+ORDER BY created_at, id;
+
+-- name: MergeFeedFollows :exec
+UPDATE feed_follows
+SET
+    feed_id = sqlc.arg(target_feed_id),
+    updated_at = NOW()
+WHERE feed_follows.feed_id = sqlc.arg(source_feed_id)
+AND feed_follows.user_id NOT IN (
+    SELECT existing.user_id FROM feed_follows existing WHERE existing.feed_id = sqlc.arg(target_feed_id)
+);
+
+-- name: MergeFeedPosts :exec
+UPDATE posts
+SET feed_id = sqlc.arg(target_feed_id)
+WHERE posts.feed_id = sqlc.arg(source_feed_id)
+AND posts.guid NOT IN (
+    SELECT existing.guid FROM posts existing WHERE existing.feed_id = sqlc.arg(target_feed_id)
+);
+
+-- name: GetConflictingFeedPosts :many
+-- Posts of the source feed that MergeFeedPosts leaves behind because the
+-- target already has their GUID, paired with the target's copy
+SELECT source.id AS duplicate_id, target.id AS keeper_id
+FROM posts source
+INNER JOIN posts target
+ON target.guid = source.guid AND target.feed_id = sqlc.arg(target_feed_id)
+WHERE source.feed_id = sqlc.arg(source_feed_id);
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1;
+
+-- name: RenameFeed :one
+UPDATE feeds
+SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetOtherFollowers :many
+-- Users other than the given one following a feed, longest-standing first
+SELECT users.id, users.name
+FROM feed_follows
+INNER JOIN users ON users.id = feed_follows.user_id
+WHERE feed_follows.feed_id = $1
+AND feed_follows.user_id <> $2
+ORDER BY feed_follows.created_at, users.name;
+
+-- name: DeactivateFeed :exec
+UPDATE feeds
+SET
+    deactivated_at = NOW(),
+    last_fetched_at = NOW(),
+    last_fetch_error = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
 -- name: CreatePost :one
 INSERT INTO posts (
     id,
@@ -105,7 +359,13 @@ INSERT INTO posts (
     url,
     description,
     published_at,
-    feed_id)
+    feed_id,
+    guid,
+    content_hash,
+    content,
+    author,
+    comments_url,
+    url_key)
 VALUES (
     $1,
     $2,
@@ -114,22 +374,78 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13,
+    $14
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id;
+RETURNING *;
 
 -- name: GetPostsForUser :many
 SELECT
     posts.id,
+    posts.created_at,
+    posts.updated_at,
     posts.title,
     posts.url,
     posts.description,
     posts.published_at,
     posts.feed_id,
-    feeds.name AS feed_name
+    posts.content,
+    posts.author,
+    posts.comments_url,
+    COALESCE((
+        SELECT string_agg(post_categories.name, ', ' ORDER BY post_categories.name)
+        FROM post_categories
+        WHERE post_categories.post_id = posts.id
+    ), '')::text AS categories,
+    COALESCE((
+        SELECT string_agg(post_tags.tag, ', ' ORDER BY post_tags.tag)
+        FROM post_tags
+        WHERE post_tags.post_id = posts.id AND post_tags.user_id = feed_follows.user_id
+    ), '')::text AS tags,
+    COALESCE(feed_follows.title_override, feeds.name)::text AS feed_name,
+    (post_reads.read_at IS NOT NULL)::boolean AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id AND post_stars.user_id = feed_follows.user_id
+    )::boolean AS is_starred,
+    posts.snapshot
 FROM posts
 INNER JOIN feeds ON posts.feed_id = feeds.id
 INNER JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
 WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
+AND NOT feed_follows.muted
+AND (sqlc.narg(folder_id)::uuid IS NULL OR feed_follows.folder_id = sqlc.narg(folder_id)::uuid)
+AND (sqlc.narg(author)::text IS NULL OR posts.author ILIKE '%' || sqlc.narg(author)::text || '%')
+AND (sqlc.narg(category)::text IS NULL OR EXISTS (
+    SELECT 1 FROM post_categories
+    WHERE post_categories.post_id = posts.id
+    AND lower(post_categories.name) = lower(sqlc.narg(category)::text)
+))
+AND (sqlc.narg(query)::text IS NULL OR (
+    posts.title ILIKE '%' || sqlc.narg(query)::text || '%'
+    OR posts.description ILIKE '%' || sqlc.narg(query)::text || '%'
+    OR posts.content ILIKE '%' || sqlc.narg(query)::text || '%'
+    OR posts.author ILIKE '%' || sqlc.narg(query)::text || '%'
+    OR EXISTS (
+        SELECT 1 FROM post_categories
+        WHERE post_categories.post_id = posts.id
+        AND post_categories.name ILIKE '%' || sqlc.narg(query)::text || '%'
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_tags
+        WHERE post_tags.post_id = posts.id
+        AND post_tags.user_id = feed_follows.user_id
+        AND post_tags.tag ILIKE '%' || sqlc.narg(query)::text || '%'
+    )
+))
+-- Newest first; each point of priority moves a feed's posts up by an hour, at most a day
+ORDER BY COALESCE(posts.published_at, posts.created_at)
+    + make_interval(hours => LEAST(GREATEST(feed_follows.priority, -24), 24)) DESC,
+    feed_follows.priority DESC
 LIMIT $2;
\ No newline at end of file
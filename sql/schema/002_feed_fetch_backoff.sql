@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN next_fetch_at TIMESTAMP WITH TIME ZONE NULL,
+ADD COLUMN last_fetch_error TEXT NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN last_fetch_error,
+DROP COLUMN next_fetch_at;
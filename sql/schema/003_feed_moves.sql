@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN deactivated_at TIMESTAMP WITH TIME ZONE NULL;
+
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL,
+    feed_id UUID NULL,
+    message TEXT NOT NULL,
+    read_at TIMESTAMP WITH TIME ZONE NULL,
+    CONSTRAINT fk_notification_user
+      FOREIGN KEY(user_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_notification_feed
+      FOREIGN KEY(feed_id)
+      REFERENCES feeds(id)
+      ON DELETE SET NULL
+);
+
+-- +goose Down
+DROP TABLE notifications;
+
+ALTER TABLE feeds
+DROP COLUMN deactivated_at;
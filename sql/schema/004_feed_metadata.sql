@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN title TEXT NULL,
+ADD COLUMN description TEXT NULL,
+ADD COLUMN site_url TEXT NULL,
+ADD COLUMN language TEXT NULL,
+ADD COLUMN image_url TEXT NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN image_url,
+DROP COLUMN language,
+DROP COLUMN site_url,
+DROP COLUMN description,
+DROP COLUMN title;
@@ -0,0 +1,29 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN guid TEXT NULL;
+
+UPDATE posts SET guid = url;
+
+ALTER TABLE posts
+ALTER COLUMN guid SET NOT NULL;
+
+-- The same article may legitimately appear in several feeds
+ALTER TABLE posts
+DROP CONSTRAINT posts_url_key;
+
+ALTER TABLE posts
+ADD CONSTRAINT posts_feed_guid_key UNIQUE (feed_id, guid);
+
+CREATE INDEX posts_url_idx ON posts (url);
+
+-- +goose Down
+DROP INDEX posts_url_idx;
+
+ALTER TABLE posts
+DROP CONSTRAINT posts_feed_guid_key;
+
+ALTER TABLE posts
+ADD CONSTRAINT posts_url_key UNIQUE (url);
+
+ALTER TABLE posts
+DROP COLUMN guid;
@@ -0,0 +1,41 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE post_revisions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    post_id UUID NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT,
+    published_at TIMESTAMP,
+    content_hash TEXT NOT NULL,
+    CONSTRAINT fk_revision_post
+      FOREIGN KEY(post_id)
+      REFERENCES posts(id)
+      ON DELETE CASCADE
+);
+
+CREATE TABLE post_reads (
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL,
+    read_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, post_id),
+    CONSTRAINT fk_read_user
+      FOREIGN KEY(user_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_read_post
+      FOREIGN KEY(post_id)
+      REFERENCES posts(id)
+      ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE post_reads;
+
+DROP TABLE post_revisions;
+
+ALTER TABLE posts
+DROP COLUMN content_hash;
@@ -0,0 +1,33 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN content TEXT NULL,
+ADD COLUMN author TEXT NULL,
+ADD COLUMN comments_url TEXT NULL;
+
+ALTER TABLE post_revisions
+ADD COLUMN content TEXT NULL;
+
+CREATE TABLE post_categories (
+    post_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    PRIMARY KEY (post_id, name),
+    CONSTRAINT fk_category_post
+      FOREIGN KEY(post_id)
+      REFERENCES posts(id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX posts_author_idx ON posts (lower(author));
+
+CREATE INDEX post_categories_name_idx ON post_categories (lower(name));
+
+-- +goose Down
+DROP TABLE post_categories;
+
+ALTER TABLE post_revisions
+DROP COLUMN content;
+
+ALTER TABLE posts
+DROP COLUMN comments_url,
+DROP COLUMN author,
+DROP COLUMN content;
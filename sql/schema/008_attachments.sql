@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE attachments (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    post_id UUID NOT NULL,
+    kind TEXT NOT NULL,
+    url TEXT NOT NULL,
+    mime_type TEXT NULL,
+    length BIGINT NULL,
+    duration_seconds INTEGER NULL,
+    episode INTEGER NULL,
+    UNIQUE(post_id, url),
+    CONSTRAINT fk_attachment_post
+      FOREIGN KEY(post_id)
+      REFERENCES posts(id)
+      ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE attachments;
@@ -0,0 +1,66 @@
+-- +goose Up
+-- Existing values are interpreted in the session time zone
+ALTER TABLE users
+ALTER COLUMN created_at TYPE TIMESTAMP WITH TIME ZONE,
+ALTER COLUMN updated_at TYPE TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE feeds
+ALTER COLUMN created_at TYPE TIMESTAMP WITH TIME ZONE,
+ALTER COLUMN updated_at TYPE TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE feed_follows
+ALTER COLUMN created_at TYPE TIMESTAMP WITH TIME ZONE,
+ALTER COLUMN updated_at TYPE TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE posts
+ALTER COLUMN created_at TYPE TIMESTAMP WITH TIME ZONE,
+ALTER COLUMN updated_at TYPE TIMESTAMP WITH TIME ZONE,
+ALTER COLUMN published_at TYPE TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE notifications
+ALTER COLUMN created_at TYPE TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE post_revisions
+ALTER COLUMN created_at TYPE TIMESTAMP WITH TIME ZONE,
+ALTER COLUMN published_at TYPE TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE post_reads
+ALTER COLUMN read_at TYPE TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE attachments
+ALTER COLUMN created_at TYPE TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX posts_published_at_idx ON posts (COALESCE(published_at, created_at) DESC);
+
+-- +goose Down
+DROP INDEX posts_published_at_idx;
+
+ALTER TABLE attachments
+ALTER COLUMN created_at TYPE TIMESTAMP;
+
+ALTER TABLE post_reads
+ALTER COLUMN read_at TYPE TIMESTAMP;
+
+ALTER TABLE post_revisions
+ALTER COLUMN created_at TYPE TIMESTAMP,
+ALTER COLUMN published_at TYPE TIMESTAMP;
+
+ALTER TABLE notifications
+ALTER COLUMN created_at TYPE TIMESTAMP;
+
+ALTER TABLE posts
+ALTER COLUMN created_at TYPE TIMESTAMP,
+ALTER COLUMN updated_at TYPE TIMESTAMP,
+ALTER COLUMN published_at TYPE TIMESTAMP;
+
+ALTER TABLE feed_follows
+ALTER COLUMN created_at TYPE TIMESTAMP,
+ALTER COLUMN updated_at TYPE TIMESTAMP;
+
+ALTER TABLE feeds
+ALTER COLUMN created_at TYPE TIMESTAMP,
+ALTER COLUMN updated_at TYPE TIMESTAMP;
+
+ALTER TABLE users
+ALTER COLUMN created_at TYPE TIMESTAMP,
+ALTER COLUMN updated_at TYPE TIMESTAMP;
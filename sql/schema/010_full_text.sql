@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN extract_full_text BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE posts ADD COLUMN article_content TEXT NULL;
+ALTER TABLE posts ADD COLUMN article_fetched_at TIMESTAMP WITH TIME ZONE NULL;
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN article_fetched_at;
+ALTER TABLE posts DROP COLUMN article_content;
+
+ALTER TABLE feeds DROP COLUMN extract_full_text;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE post_stars (
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL,
+    starred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, post_id),
+    CONSTRAINT fk_star_user
+      FOREIGN KEY(user_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_star_post
+      FOREIGN KEY(post_id)
+      REFERENCES posts(id)
+      ON DELETE CASCADE
+);
+
+-- snapshot is the path of the archived HTML, relative to the archive directory
+ALTER TABLE posts ADD COLUMN snapshot TEXT NULL;
+ALTER TABLE posts ADD COLUMN archived_at TIMESTAMP WITH TIME ZONE NULL;
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN archived_at;
+ALTER TABLE posts DROP COLUMN snapshot;
+
+DROP TABLE post_stars;
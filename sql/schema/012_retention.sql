@@ -0,0 +1,22 @@
+-- +goose Up
+-- Per-feed retention overrides the config; NULL means use the global setting, 0 means no limit
+ALTER TABLE feeds ADD COLUMN retention_days INTEGER NULL;
+ALTER TABLE feeds ADD COLUMN retention_items INTEGER NULL;
+
+-- Pruned items are remembered so the next fetch does not bring them back
+CREATE TABLE pruned_posts (
+    feed_id UUID NOT NULL,
+    guid TEXT NOT NULL,
+    pruned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (feed_id, guid),
+    CONSTRAINT fk_pruned_feed
+      FOREIGN KEY(feed_id)
+      REFERENCES feeds(id)
+      ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE pruned_posts;
+
+ALTER TABLE feeds DROP COLUMN retention_items;
+ALTER TABLE feeds DROP COLUMN retention_days;
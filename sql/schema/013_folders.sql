@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE folders (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    UNIQUE(user_id, name),
+    CONSTRAINT fk_folder_user
+      FOREIGN KEY(user_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE
+);
+
+-- Deleting a folder moves its feeds back to the top level
+ALTER TABLE feed_follows
+ADD COLUMN folder_id UUID NULL
+CONSTRAINT fk_follow_folder
+  REFERENCES folders(id)
+  ON DELETE SET NULL;
+
+CREATE INDEX feed_follows_folder_idx ON feed_follows (folder_id);
+
+-- +goose Down
+ALTER TABLE feed_follows DROP COLUMN folder_id;
+
+DROP TABLE folders;
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE post_tags (
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, post_id, tag),
+    CONSTRAINT fk_tag_user
+      FOREIGN KEY(user_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_tag_post
+      FOREIGN KEY(post_id)
+      REFERENCES posts(id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX post_tags_tag_idx ON post_tags (user_id, tag);
+
+-- +goose Down
+DROP TABLE post_tags;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE feed_follows
+ADD COLUMN title_override TEXT NULL,
+ADD COLUMN priority INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN muted BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN notify BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE notifications
+ADD COLUMN post_id UUID NULL
+CONSTRAINT fk_notification_post
+  REFERENCES posts(id)
+  ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE notifications DROP COLUMN post_id;
+
+ALTER TABLE feed_follows
+DROP COLUMN notify,
+DROP COLUMN muted,
+DROP COLUMN priority,
+DROP COLUMN title_override;
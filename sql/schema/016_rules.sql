@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL,
+    -- title, description, author, feed, category or any
+    field TEXT NOT NULL,
+    -- contains or regex
+    match_type TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    -- hide, read, star, tag or notify
+    action TEXT NOT NULL,
+    -- the tag for the tag action
+    action_arg TEXT NULL,
+    CONSTRAINT fk_rule_user
+      FOREIGN KEY(user_id)
+      REFERENCES users(id)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX rules_user_idx ON rules (user_id);
+
+-- +goose Down
+DROP TABLE rules;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- url_key is the URL without scheme, www., trailing slash and tracking
+-- parameters, used to find feeds and posts regardless of how their URL was
+-- spelled. Existing rows get theirs from the canonicalize command.
+ALTER TABLE feeds ADD COLUMN url_key TEXT NULL;
+CREATE UNIQUE INDEX feeds_url_key_idx ON feeds (url_key);
+
+ALTER TABLE posts ADD COLUMN url_key TEXT NULL;
+CREATE INDEX posts_url_key_idx ON posts (feed_id, url_key);
+
+-- +goose Down
+DROP INDEX posts_url_key_idx;
+ALTER TABLE posts DROP COLUMN url_key;
+
+DROP INDEX feeds_url_key_idx;
+ALTER TABLE feeds DROP COLUMN url_key;
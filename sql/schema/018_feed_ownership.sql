@@ -0,0 +1,10 @@
+-- +goose Up
+-- Admins may edit and delete any feed, others only the feeds they added.
+-- The first user to register is the admin.
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE users SET is_admin = TRUE
+WHERE id = (SELECT id FROM users ORDER BY created_at, id LIMIT 1);
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN is_admin;
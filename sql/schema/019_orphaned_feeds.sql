@@ -0,0 +1,12 @@
+-- +goose Up
+-- orphaned_at is when a feed was first seen with no followers; reap deletes
+-- or archives it once the grace period has passed
+ALTER TABLE feeds ADD COLUMN orphaned_at TIMESTAMP WITH TIME ZONE NULL;
+
+UPDATE feeds SET orphaned_at = NOW()
+WHERE NOT EXISTS (
+    SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id
+);
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN orphaned_at;